@@ -3,7 +3,6 @@ module github.com/kamilszymczak/event-dispatcher
 go 1.22
 
 require (
-	github.com/jonboulle/clockwork v0.4.0
 	github.com/mixer/clock v0.0.0-20220922162503-4933054921a2
 	gopkg.in/yaml.v3 v3.0.1
 )
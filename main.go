@@ -1,22 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/kamilszymczak/event-dispatcher/poller"
 	"github.com/kamilszymczak/event-dispatcher/response"
 )
 
+// time given to polls in flight to finish once a signal is received
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// refresh rate should restart rate countdown again once request is received, not strictly every x interval
 	// since getting read from api could take time, and we don't want queued 'messages' to the api
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Produce - fetch from api
 	pollerService := poller.New("https://prod-public-api.livescore.com/v1/api/app/scoreboard/soccer/", response.LivescoreData{})
 	pollerService.AddObservable(poller.Observable{Address: "909663"})
 
-	listenChan := pollerService.Listen()
+	listenChan := pollerService.Listen(ctx)
 
 	P:
 	for {
@@ -26,8 +37,16 @@ func main() {
 				fmt.Println("Publish channel closing")
 				break P
 			}
-			typedRes, _ := pollerService.HandleEvent(req)
-			log.Print("received: ", typedRes)
+			log.Print("received: ", req.Response)
+		case <-ctx.Done():
+			fmt.Println("Shutting down")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := pollerService.Shutdown(shutdownCtx)
+			cancel()
+			if err != nil {
+				log.Print("shutdown: ", err)
+			}
+			break P
 		}
 	}
 
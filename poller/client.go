@@ -0,0 +1,109 @@
+package poller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultUserAgent identifies the poller to the api unless set with WithUserAgent
+const defaultUserAgent = "event-dispatcher"
+
+// WithHTTPClient sends requests with the given client, e.g. one with a stub
+// RoundTripper in tests. The proxy and TLS options don't apply to it.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *poller) {
+		p.client = c
+	}
+}
+
+// WithTimeout limits how long a single request may take, including reading its body
+func WithTimeout(d time.Duration) Option {
+	return func(p *poller) {
+		p.timeout = d
+	}
+}
+
+// WithProxy sends requests through the proxy at the given url
+func WithProxy(proxy *url.URL) Option {
+	return func(p *poller) {
+		p.proxy = proxy
+	}
+}
+
+// WithRootCAs trusts the given certificate authorities instead of the system ones
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(p *poller) {
+		p.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithClientCertificates presents the certificates to apis requiring mutual TLS
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return func(p *poller) {
+		p.tlsConfig().Certificates = certs
+	}
+}
+
+// WithMinTLSVersion refuses connections below the given version, e.g. tls.VersionTLS13
+func WithMinTLSVersion(version uint16) Option {
+	return func(p *poller) {
+		p.tlsConfig().MinVersion = version
+	}
+}
+
+// WithHeader sends the header with every request, observables' own headers take precedence
+func WithHeader(key, value string) Option {
+	return func(p *poller) {
+		p.header.Add(key, value)
+	}
+}
+
+// WithUserAgent replaces the default User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(p *poller) {
+		p.header.Set("User-Agent", userAgent)
+	}
+}
+
+func (p *poller) tlsConfig() *tls.Config {
+	if p.tls == nil {
+		p.tls = &tls.Config{}
+	}
+	return p.tls
+}
+
+// newClient builds the client for the proxy and TLS options
+func (p *poller) newClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if p.proxy != nil {
+		transport.Proxy = http.ProxyURL(p.proxy)
+	}
+	if p.tls != nil {
+		transport.TLSClientConfig = p.tls
+	}
+	return &http.Client{Transport: transport}
+}
+
+// httpClient returns the client requests are sent with
+func (t *poller) httpClient() *http.Client {
+	if t.client == nil {
+		return http.DefaultClient
+	}
+	return t.client
+}
+
+// setHeaders adds the default headers followed by the observable's to the request
+func (t *poller) setHeaders(req *http.Request, observable Observable) {
+	for key, values := range t.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range observable.Header {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package poller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamilszymczak/event-dispatcher/response"
+)
+
+// roundTripFunc stubs the transport of the poller's client
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientSendsHeaders(t *testing.T) {
+	var got *http.Request
+	stub := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"Eid": "909663"}`))}, nil
+	})
+
+	testCases := []struct {
+		name          string
+		opts          []Option
+		observable    Observable
+		wantUserAgent string
+		wantKey       string
+	}{
+		{
+			name:          "default user agent",
+			wantUserAgent: defaultUserAgent,
+		},
+		{
+			name:          "default headers",
+			opts:          []Option{WithUserAgent("tracker/1.0"), WithHeader("X-Api-Key", "secret")},
+			wantUserAgent: "tracker/1.0",
+			wantKey:       "secret",
+		},
+		{
+			name:          "observable headers take precedence",
+			opts:          []Option{WithHeader("X-Api-Key", "secret")},
+			observable:    Observable{Header: http.Header{"X-Api-Key": {"own"}}},
+			wantUserAgent: defaultUserAgent,
+			wantKey:       "own",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithHTTPClient(&http.Client{Transport: stub}), WithInterval(time.Minute)}, tc.opts...)
+			poller := New("https://livescore.test/", response.LivescoreData{}, opts...)
+
+			if _, err := poller.fetchData(context.Background(), tc.observable); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ua := got.Header.Get("User-Agent"); ua != tc.wantUserAgent {
+				t.Errorf("Expected %v got %v", tc.wantUserAgent, ua)
+			}
+			if key := got.Header.Values("X-Api-Key"); strings.Join(key, ",") != tc.wantKey {
+				t.Errorf("Expected %v got %v", tc.wantKey, key)
+			}
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	stub := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	poller := New("https://livescore.test/", response.LivescoreData{}, WithHTTPClient(&http.Client{Transport: stub}), WithTimeout(10*time.Millisecond), WithInterval(time.Minute))
+
+	_, err := poller.fetchData(context.Background(), Observable{Address: "909663"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestClientTransportOptions(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.corp:3128")
+	cas := x509.NewCertPool()
+	poller := New("https://livescore.test/", response.LivescoreData{}, WithProxy(proxy), WithRootCAs(cas), WithMinTLSVersion(tls.VersionTLS13), WithInterval(time.Minute))
+
+	transport := poller.client.Transport.(*http.Transport)
+	req, _ := http.NewRequest(http.MethodGet, "https://livescore.test/909663", nil)
+	if got, _ := transport.Proxy(req); got.String() != proxy.String() {
+		t.Errorf("Expected %v got %v", proxy, got)
+	}
+	if transport.TLSClientConfig.RootCAs != cas {
+		t.Errorf("Expected custom root CAs")
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected %v got %v", tls.VersionTLS13, transport.TLSClientConfig.MinVersion)
+	}
+}
+
+func TestClientTrustsCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Eid": "909663"}`))
+	}))
+	defer server.Close()
+
+	cas := x509.NewCertPool()
+	cas.AddCert(server.Certificate())
+
+	testCases := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "unknown authority", wantErr: true},
+		{name: "custom CA", opts: []Option{WithRootCAs(cas)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			poller := New(server.URL+"/", response.LivescoreData{}, append(tc.opts, WithInterval(time.Minute))...)
+			_, err := poller.fetchData(context.Background(), Observable{Address: "909663"})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
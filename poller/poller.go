@@ -1,19 +1,22 @@
 package poller
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/jonboulle/clockwork"
 	"github.com/kamilszymczak/event-dispatcher/config"
 	"github.com/kamilszymczak/event-dispatcher/response"
 	"github.com/kamilszymczak/event-dispatcher/scheduler"
+	"github.com/mixer/clock"
 )
 
 type Event struct {
@@ -21,86 +24,317 @@ type Event struct {
 	Observable *Observable
 }
 
+// Observable is polled at the poller's api url followed by its address,
+// the address identifies it within the poller
 type Observable struct {
-	Address  string
-	interval *time.Duration
+	Address string
+	// Time between polls, the poller's default interval when zero
+	Interval time.Duration
+	// Query parameters added to the url
+	Query url.Values
+	// Headers sent with every request
+	Header http.Header
+	// URL polled instead of the poller's api url followed by the address
+	URL string
 }
 
 type dispatchFunc func(response.ResponseAccessor) bool
 
+// intervalFunc decides when an observable is polled next from its latest response
+type intervalFunc func(response.ResponseAccessor) time.Duration
+
 type poller struct {
 	apiUrl       string
 	interval     time.Duration
 	observables  []Observable
 	eventChan    chan Event
 	errorHandler func(err error)
-	jobsRunning  sync.WaitGroup
+	errorChan    chan PollError
+	// guards observables and the fields below, set by Listen
+	mu           sync.Mutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	done         chan struct{}
+	// jobs scheduled and not finished yet
+	running      int
+	closed       bool
+	// keeps the channels open for observables added later once every job finished
+	keepListening bool
 	responseType response.Responser
-	jobs         map[string]*scheduler.Job
+	jobs         scheduler.Scheduler
 	dispatchFunc dispatchFunc
+	intervalFunc intervalFunc
 	shuffle 	 *delay
 	stopObservableAfterDispatch	bool
+	retryPolicy  scheduler.RetryPolicy
+	windows      []scheduler.Window
+	limiter      *scheduler.Limiter
+	jitter       float64
+	clock        clock.Clock
+	client       *http.Client
+	// per request, unlimited when zero
+	timeout      time.Duration
+	proxy        *url.URL
+	tls          *tls.Config
+	// sent with every request
+	header       http.Header
+}
+
+// PollError describes a failed poll of an observable, after any retries
+type PollError struct {
+	Observable string
+	// Run of the observable's job the poll failed on, starting at 1
+	Attempt int
+	// Status of the api's response, zero if there was none
+	StatusCode int
+	Err        error
+	Time       time.Time
+}
+
+func (e PollError) Error() string {
+	return fmt.Sprintf("polling %q attempt %d: %v", e.Observable, e.Attempt, e.Err)
+}
+
+func (e PollError) Unwrap() error {
+	return e.Err
+}
+
+// errorBuffer is how many errors Errors holds for a slow reader before dropping them
+const errorBuffer = 64
+
+// statusError is returned when the api responds with anything but 200 OK
+type statusError struct {
+	StatusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("fetching data unsuccessful, response status: %d", e.StatusCode)
+}
+
+// isServerError reports if the error is a 5xx response, which is usually transient
+func isServerError(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+var defaultRetryPolicy = scheduler.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+	Retryable:      isServerError,
+}
+
+// Option configures a poller created with New
+type Option func(*poller)
+
+// WithClock drives polling by the given clock, e.g. a mock clock in tests
+func WithClock(c clock.Clock) Option {
+	return func(p *poller) {
+		p.clock = c
+	}
 }
 
-func New(url string, responseObject response.Responser) *poller {
-	rate := config.GetConfig().FetchRate()
+// WithInterval sets the default interval of observables, overriding the fetch rate from the config
+func WithInterval(d time.Duration) Option {
+	return func(p *poller) {
+		p.interval = d
+	}
+}
 
-	return &poller{
+// WithShuffleGap sets the gap between start times of shuffled observables,
+// overriding the delay gap from the config
+func WithShuffleGap(d time.Duration) Option {
+	return func(p *poller) {
+		p.shuffle.gap = d
+	}
+}
+
+func New(url string, responseObject response.Responser, opts ...Option) *poller {
+	p := &poller{
 		apiUrl:       url,
-		interval:     time.Duration(rate) * time.Millisecond,
 		observables:  make([]Observable, 0),
 		eventChan:    make(chan Event),
+		errorChan:    make(chan PollError, errorBuffer),
 		responseType: responseObject,
-		jobs:		  make(map[string]*scheduler.Job),
-		shuffle:	  newDelay(),
+		shuffle:      newDelay(),
+		retryPolicy:  defaultRetryPolicy,
+		clock:        scheduler.RealClock,
+		header:       http.Header{"User-Agent": {defaultUserAgent}},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.client == nil {
+		p.client = p.newClient()
+	}
+	if p.interval == 0 {
+		p.interval = time.Duration(config.GetConfig().FetchRate()) * time.Millisecond
 	}
+	p.jobs = scheduler.New(scheduler.WithClock(p.clock))
+	return p
 }
 
-func (p *poller) Listen() <-chan Event {
+// Listen starts polling the observables and returns the channel events are
+// delivered on. Observables can be added and removed while listening.
+// The channel is closed once every observable's polling finished, or after
+// ctx is done or Shutdown is called and the last poll finished. See
+// KeepListening to keep it open for observables added later.
+func (p *poller) Listen(ctx context.Context) <-chan Event {
 	slog.Info("Poller listener started", "poller endpoint", p.apiUrl)
-	p.jobsRunning.Add(len(p.observables))
 
+	p.mu.Lock()
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
 	for _, obs := range p.observables {
-		slog.Info("Scheduling job.", "observable", obs)
+		slog.Info("Scheduling job.", "observable", obs.Address)
+		p.running++
 		scheduleJob(p, obs)
 	}
+	p.mu.Unlock()
 
-	go p.waitForJobsToComplete()
+	go p.stopOnDone()
+	// nothing to poll
+	p.closeIfFinished()
 	return p.eventChan
 }
 
+// Shutdown stops polling and waits for polls in flight to finish, returns
+// ctx's error if they don't finish in time.
+func (p *poller) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.mu.Unlock()
+
+	// never listened
+	if cancel == nil {
+		p.stopAll()
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopOnDone stops all jobs once the listening context is done
+func (p *poller) stopOnDone() {
+	<-p.ctx.Done()
+	p.stopAll()
+	p.closeIfFinished()
+}
+
 func (t *poller) executeJob(observable Observable) {
-	defer t.jobsRunning.Done()
+	job, ok := t.addJob(observable)
+	if !ok {
+		t.jobDone()
+		return
+	}
+	job.DoAdaptive(func(ctx context.Context) (time.Duration, error) {
+		return t.poolData(ctx, observable)
+	})
+}
+
+// addJob registers the observable's job with the scheduler
+func (t *poller) addJob(observable Observable) (*scheduler.Job, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// removed or shut down while the start of a shuffled observable was delayed
+	if t.indexOf(observable.Address) < 0 || t.ctx.Err() != nil {
+		return nil, false
+	}
+
+	ticker := t.clock.NewTicker(observable.Interval)
+	// a fetch outlasting the interval should not queue up another request to the api
+	job := scheduler.Every(ticker).Interval(observable.Interval).SkipOverlapping().Retry(t.retryPolicy).Within(t.windows...).Limit(t.limiter).Jitter(t.jitter).OnError(t.reportError).OnComplete(t.jobDone)
+	if err := t.jobs.Add(observable.Address, job); err != nil {
+		slog.Warn("Observable already scheduled.", "observable", observable.Address, "error", err)
+		ticker.Stop()
+		return nil, false
+	}
+	return job, true
+}
+
+// jobDone is called once an observable's job finished
+func (p *poller) jobDone() {
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
 
-	ticker := clockwork.NewRealClock().NewTicker(*observable.interval)
-	job := scheduler.Every(ticker).Do(t.poolData, observable)
-	t.jobs[observable.Address] = job
-	job.Wait()
+	p.closeIfFinished()
 }
 
-func (t *poller) fetchData(observable Observable) ([]byte, error) {
+func (t *poller) fetchData(ctx context.Context, observable Observable) ([]byte, error) {
 	slog.Info("Pooling data started.", "observable", observable.Address)
 
-	resp, err := http.Get(fmt.Sprintf("%s%s", t.apiUrl, observable.Address))
+	address, err := t.requestURL(observable)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	t.setHeaders(req, observable)
+
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		slog.Warn("Fetching data unsuccessful.", "observable address", observable.Address, "response status", resp.StatusCode)
-		t.jobs[observable.Address].Cancel()
-		return nil, fmt.Errorf("fetching data unsuccessful, response status: %d", resp.StatusCode)
+		// server errors are left to the retry policy, anything else won't go away by asking again
+		if resp.StatusCode < http.StatusInternalServerError {
+			t.jobs.Stop(observable.Address)
+		}
+		return nil, &statusError{StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	return body, nil
 }
 
+// requestURL builds the url the observable is polled at
+func (t *poller) requestURL(observable Observable) (string, error) {
+	address := observable.URL
+	if address == "" {
+		address = fmt.Sprintf("%s%s", t.apiUrl, observable.Address)
+	}
+	if len(observable.Query) == 0 {
+		return address, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, values := range observable.Query {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
 func parseData(p *poller, body []byte) (response.ResponseAccessor, error) {
 	typedResponse, err := p.responseType.Unmarshal(body)
 
@@ -123,11 +357,12 @@ func buildEvent(observable Observable, response response.ResponseAccessor) Event
 	}
 }
 
-func (t *poller) poolData(observable Observable) {
-	data, err := t.fetchData(observable)
+// poolData fetches the observable and dispatches its event, returns the delay
+// until the next poll suggested by the interval func, zero if there is none
+func (t *poller) poolData(ctx context.Context, observable Observable) (time.Duration, error) {
+	data, err := t.fetchData(ctx, observable)
 	if err != nil {
-		log.Print(err.Error())
-		return
+		return 0, err
 	}
 
 	parsedResponse, _ := parseData(t, data)
@@ -135,54 +370,291 @@ func (t *poller) poolData(observable Observable) {
 	slog.Info("Response parsed and event built.", "observable address", event.Observable.Address,"event response", event.Response)
 
 	if t.dispatchFunc == nil {
-		t.eventChan <- event
-		return
+		if err := t.send(ctx, event); err != nil {
+			return 0, err
+		}
+		return t.nextInterval(parsedResponse), nil
 	}
 
 	if t.dispatchFunc(event.Response) {
-		t.eventChan <- event
+		if err := t.send(ctx, event); err != nil {
+			return 0, err
+		}
 
 		if t.stopObservableAfterDispatch {
 			slog.Info("Observable's response has been dispatched, cancelling it's job.", "observable", observable.Address)
-			t.jobs[observable.Address].Cancel()
+			t.jobs.Stop(observable.Address)
 		}
 	}
+	return t.nextInterval(parsedResponse), nil
+}
+
+// send delivers the event, gives up if the observable is stopped meanwhile
+func (t *poller) send(ctx context.Context, event Event) error {
+	select {
+	case t.eventChan <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *poller) nextInterval(resp response.ResponseAccessor) time.Duration {
+	if t.intervalFunc == nil || resp == nil {
+		return 0
+	}
+	return t.intervalFunc(resp)
 }
 
 func (p *poller) GetEventChannel() <-chan Event {
 	return p.eventChan
 }
 
+// Errors delivers failed polls, it is closed together with the event channel.
+// Errors are dropped while the channel's buffer is full.
+func (p *poller) Errors() <-chan PollError {
+	return p.errorChan
+}
+
+// SetErrorHandler sets a callback called with a PollError for every failed poll.
+// Without a handler failures are logged.
+func (p *poller) SetErrorHandler(fn func(err error)) {
+	p.errorHandler = fn
+}
+
+// reportError passes a failed run of an observable's job on to the error handler and channel
+func (t *poller) reportError(jobErr *scheduler.JobError) {
+	// polls cut short by stopping the observable aren't failures
+	if errors.Is(jobErr.Err, context.Canceled) {
+		return
+	}
+
+	pollErr := PollError{
+		Observable: jobErr.Job,
+		Attempt:    jobErr.Attempt,
+		Err:        jobErr.Err,
+		Time:       t.clock.Now(),
+	}
+	var statusErr *statusError
+	if errors.As(jobErr.Err, &statusErr) {
+		pollErr.StatusCode = statusErr.StatusCode
+	}
+
+	if t.errorHandler != nil {
+		t.errorHandler(pollErr)
+	} else {
+		slog.Error("Polling observable failed.", "observable", pollErr.Observable, "attempt", pollErr.Attempt, "error", pollErr.Err)
+	}
+
+	select {
+	case t.errorChan <- pollErr:
+	default:
+		slog.Warn("Error channel full, dropping error.", "observable", pollErr.Observable)
+	}
+}
+
+// AddObservable tracks the observables, while listening polling them starts
+// straight away. Observables already being polled are left as they are, see
+// UpdateObservable.
 func (p *poller) AddObservable(obs ...Observable) {
-	for i, o := range obs {
-		if o.interval == nil {
-			obs[i].interval = &p.interval
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, o := range obs {
+		if o.Interval == 0 {
+			o.Interval = p.interval
 		}
+
+		i := p.indexOf(o.Address)
+		switch {
+		case i < 0:
+			p.observables = append(p.observables, o)
+		case p.polling(o.Address):
+			slog.Warn("Observable already scheduled.", "observable", o.Address)
+			continue
+		default:
+			p.observables[i] = o
+		}
+
+		if p.reserve() {
+			slog.Info("Scheduling job.", "observable", o.Address)
+			scheduleJob(p, o)
+		}
+	}
+}
+
+// RemoveObservable stops polling the observable with the given address,
+// returns false if there is no such observable
+func (p *poller) RemoveObservable(address string) bool {
+	p.mu.Lock()
+	i := p.indexOf(address)
+	if i < 0 {
+		p.mu.Unlock()
+		return false
 	}
+	p.observables = slices.Delete(p.observables, i, i+1)
+	p.mu.Unlock()
 
-	p.observables = append(p.observables, obs...)
+	p.jobs.Stop(address)
+	return true
 }
 
-func (p *poller) waitForJobsToComplete() {
-	defer close(p.eventChan)
-	p.jobsRunning.Wait()
+// UpdateObservable replaces the observable with the same address, while
+// listening its polling restarts with the new settings. Returns false if
+// there is no such observable.
+func (p *poller) UpdateObservable(o Observable) bool {
+	if o.Interval == 0 {
+		o.Interval = p.interval
+	}
+
+	p.mu.Lock()
+	i := p.indexOf(o.Address)
+	if i < 0 {
+		p.mu.Unlock()
+		return false
+	}
+	p.observables[i] = o
+	// reserved before the old job stops so the channels stay open
+	restart := p.reserve()
+	p.mu.Unlock()
+
+	if !restart {
+		return true
+	}
+	if job, ok := p.jobs.Job(o.Address); ok {
+		job.Stop()
+		job.Wait()
+	}
+
+	p.mu.Lock()
+	scheduleJob(p, o)
+	p.mu.Unlock()
+	return true
+}
+
+// indexOf finds the observable by address, callers hold mu
+func (p *poller) indexOf(address string) int {
+	return slices.IndexFunc(p.observables, func(o Observable) bool {
+		return o.Address == address
+	})
+}
+
+// polling reports if the observable's job is running
+func (p *poller) polling(address string) bool {
+	job, ok := p.jobs.Job(address)
+	return ok && job.Ctx.Err() == nil
+}
+
+// reserve counts in a job about to be scheduled, returns false if the poller
+// isn't listening. Callers hold mu.
+func (p *poller) reserve() bool {
+	if p.ctx == nil || p.ctx.Err() != nil {
+		return false
+	}
+	p.running++
+	return true
+}
+
+// closeIfFinished closes the channels once the last job finished, unless the
+// poller keeps listening for observables added later
+func (p *poller) closeIfFinished() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || p.running > 0 || (p.keepListening && p.ctx.Err() == nil) {
+		return
+	}
+	p.closed = true
+	p.cancel()
+	close(p.eventChan)
+	close(p.errorChan)
+	close(p.done)
 	slog.Info("All jobs finished running, closing listener channel.")
 }
 
 func (p *poller) stopAll() {
-	for _, j := range p.jobs {
-		j.Stop()
+	p.jobs.StopAll()
+}
+
+// Stats returns run statistics of every scheduled observable by address
+func (p *poller) Stats() map[string]scheduler.Stats {
+	stats := make(map[string]scheduler.Stats)
+	for _, address := range p.jobs.Jobs() {
+		if job, ok := p.jobs.Job(address); ok {
+			stats[address] = job.Stats()
+		}
 	}
+	return stats
 }
 
 func (p *poller) SetDispatchFunc(fn dispatchFunc) {
 	p.dispatchFunc = fn
 }
 
+// SetIntervalFunc lets every response decide when its observable is polled next.
+// A positive interval replaces the observable's, zero keeps it and a negative
+// one stops polling the observable.
+func (p *poller) SetIntervalFunc(fn intervalFunc) {
+	p.intervalFunc = fn
+}
+
+// MatchInterval polls a match every preMatch until kick off and every live
+// while it is played, polling stops at full time
+func MatchInterval(preMatch, live time.Duration) intervalFunc {
+	return func(resp response.ResponseAccessor) time.Duration {
+		switch resp.GetGameStatus() {
+		case 0:
+			return preMatch
+		case 1:
+			return live
+		default:
+			return -1
+		}
+	}
+}
+
+// KeepListening keeps the event channel open after every observable's polling
+// finished, so observables can still be added. The channel is then only closed
+// once the listening context is done or Shutdown is called.
+func (p *poller) KeepListening(toggle bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keepListening = toggle
+}
+
 func (p *poller) StopObservableAfterDispatched(toggle bool) {
 	p.stopObservableAfterDispatch = toggle
 }
 
+// SetActiveWindows limits polling to the given windows, e.g. when matches are played.
+// Observables are polled around the clock when no window is set.
+func (p *poller) SetActiveWindows(windows ...scheduler.Window) {
+	p.windows = windows
+}
+
+// SetRetryPolicy replaces the default policy of retrying 5xx responses
+func (p *poller) SetRetryPolicy(policy scheduler.RetryPolicy) {
+	p.retryPolicy = policy
+}
+
+// SetRateLimit caps the requests to the api across all observables,
+// retries included, to stay within its quota. A rate of zero or less is
+// ignored, as it would stop polling altogether.
+func (p *poller) SetRateLimit(perSecond float64, burst int) {
+	if perSecond <= 0 {
+		slog.Warn("Ignoring rate limit without a positive rate.", "rate", perSecond)
+		return
+	}
+	p.limiter = scheduler.NewLimiter(perSecond, burst, scheduler.WithLimiterClock(p.clock))
+}
+
+// SetJitter spreads polls by ±fraction of the observable's interval, so
+// observables and replicas don't hit the api in sync
+func (p *poller) SetJitter(fraction float64) {
+	p.jitter = fraction
+}
+
 type delay struct {
 	current	time.Duration
 	toggle	bool
@@ -196,11 +668,12 @@ func (p *poller) Shuffle(toggle bool) {
 	p.shuffle.toggle = toggle
 }
 
+// newDelay leaves the gap to the config unless set with WithShuffleGap
 func newDelay() *delay {
 	return &delay{
 		current: 0,
 		toggle: false,
-		gap: time.Duration(config.GetConfig().Request.DelayGap) * time.Millisecond,
+		gap: -1,
 	}
 }
 
@@ -218,10 +691,13 @@ func delayJob(p *poller, observable Observable) {
 		p.executeJob(observable)
 	}
 
-	p.shuffle.delayFunction(job)	
+	p.shuffle.delayFunction(p.clock, job)
 }
 
-func (d *delay) delayFunction(fn func()) {
-	time.AfterFunc(d.current, fn)
+func (d *delay) delayFunction(c clock.Clock, fn func()) {
+	if d.gap < 0 {
+		d.gap = time.Duration(config.GetConfig().Request.DelayGap) * time.Millisecond
+	}
+	c.AfterFunc(d.current, fn)
 	d.current += d.gap
 }
\ No newline at end of file
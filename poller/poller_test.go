@@ -1,14 +1,21 @@
 package poller
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kamilszymczak/event-dispatcher/response"
+	"github.com/kamilszymczak/event-dispatcher/scheduler"
+	"github.com/mixer/clock"
 )
 
 func TestPoolingDataFromLivescore(t *testing.T) {
@@ -33,7 +40,7 @@ func TestPoolingDataFromLivescore(t *testing.T) {
 
 	obs := Observable{Address: ""}
 
-	data, _ := poller.fetchData(obs)
+	data, _ := poller.fetchData(context.Background(), obs)
 	got, err := parseData(poller, data)
 
 	if err != nil {
@@ -69,7 +76,7 @@ func TestPoolingDataFromLivescoreIncorrectJSONFormat(t *testing.T) {
 
 	obs := Observable{Address: ""}
 
-	data, _ := poller.fetchData(obs)
+	data, _ := poller.fetchData(context.Background(), obs)
 	got, err := parseData(poller, data)
 
 	if err != nil {
@@ -86,11 +93,11 @@ func TestPoolingDataFromLivescoreIncorrectJSONFormat(t *testing.T) {
 }
 
 func TestPoolingDataFromLivescoreInvalidAddress(t *testing.T) {
-	poller := &poller{apiUrl: "https://prod-public-api.livescore.com/v1/api/app/scoreboard/soccer/", responseType: response.LivescoreData{}}
+	poller := &poller{apiUrl: "https://prod-public-api.livescore.com/v1/api/app/scoreboard/soccer/", responseType: response.LivescoreData{}, jobs: scheduler.New()}
 	obs := Observable{Address: "108583400"}
 	want := 410
 
-	_, err := poller.fetchData(obs)
+	_, err := poller.fetchData(context.Background(), obs)
 
 	if !strings.Contains(err.Error(), strconv.Itoa(want)) {
 		t.Errorf("Expected error to contain status code %v got %v", 410, err.Error())
@@ -131,4 +138,383 @@ func TestDontDispatch(t *testing.T) {
 	if matchOngoing != false {
 		t.Errorf("Expected %v got %v", false, matchOngoing)
 	}
-}
\ No newline at end of file
+}
+func TestServerErrorsAreRetryable(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		want   bool
+	}{
+		{name: "service unavailable is retried", status: http.StatusServiceUnavailable, want: true},
+		{name: "gone is not retried", status: http.StatusGone, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			poller := &poller{apiUrl: server.URL, responseType: response.LivescoreData{}, jobs: scheduler.New()}
+			_, err := poller.fetchData(context.Background(), Observable{Address: ""})
+
+			if !strings.Contains(err.Error(), strconv.Itoa(tc.status)) {
+				t.Errorf("Expected error to contain status code %v got %v", tc.status, err.Error())
+			}
+			if got := isServerError(err); got != tc.want {
+				t.Errorf("Expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchInterval(t *testing.T) {
+	interval := MatchInterval(time.Minute, 5*time.Second)
+
+	testCases := []struct {
+		name   string
+		status int
+		want   time.Duration
+	}{
+		{name: "pre-match", status: 0, want: time.Minute},
+		{name: "live", status: 1, want: 5 * time.Second},
+		{name: "full time stops polling", status: 2, want: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := interval(response.LivescoreData{EventFinished: tc.status}); got != tc.want {
+				t.Errorf("Expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPoolDataSuggestsNextInterval(t *testing.T) {
+	rawJson, _ := os.ReadFile("./poller_livescoreOutputValid_test.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rawJson))
+	}))
+	defer server.Close()
+
+	poller := &poller{apiUrl: server.URL, responseType: response.LivescoreData{}, eventChan: make(chan Event, 1)}
+	poller.SetIntervalFunc(MatchInterval(time.Minute, 5*time.Second))
+
+	next, err := poller.poolData(context.Background(), Observable{Address: ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the match in the response is over
+	if next >= 0 {
+		t.Errorf("Expected negative interval got %v", next)
+	}
+}
+
+func TestPollingMatchWithMockClock(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 15, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// pre-match, live and full time on consecutive polls
+		status := len(requests)
+		requests = append(requests, fc.Now())
+		fmt.Fprintf(w, `{"Eid": "909663", "Epr": %d}`, status)
+	}))
+	defer server.Close()
+
+	poller := New(server.URL+"/", response.LivescoreData{}, WithClock(fc), WithInterval(time.Minute))
+	poller.SetIntervalFunc(MatchInterval(time.Minute, 5*time.Second))
+	poller.AddObservable(Observable{Address: "909663"})
+	events := poller.Listen(context.Background())
+
+	// every poll is received before the clock moves on to the next one
+	for _, next := range []time.Duration{time.Minute, 5 * time.Second} {
+		<-events
+		due := fc.Now().Add(next)
+		waitFor(t, func() bool {
+			return poller.Stats()["909663"].NextRun.Equal(due)
+		})
+		fc.AddTime(next)
+	}
+	<-events
+
+	// polling stops at full time
+	if _, ok := <-events; ok {
+		t.Errorf("Expected event channel to be closed")
+	}
+
+	want := []time.Time{start, start.Add(time.Minute), start.Add(time.Minute + 5*time.Second)}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("Expected requests at %v got %v", want, requests)
+	}
+}
+
+// waitFor polls the condition until it holds, failing the test after a second
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFailedPollsAreReported(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 15, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	// nothing listens on the address of a closed server
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	testCases := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "error response", url: server.URL + "/", wantStatus: http.StatusNotFound},
+		{name: "transport error", url: closed.URL + "/", wantStatus: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var handled error
+			poller := New(tc.url, response.LivescoreData{}, WithClock(fc), WithInterval(time.Minute))
+			poller.SetRetryPolicy(scheduler.RetryPolicy{})
+			poller.SetErrorHandler(func(err error) {
+				handled = err
+			})
+			poller.AddObservable(Observable{Address: "909663"})
+			events := poller.Listen(context.Background())
+
+			got := <-poller.Errors()
+			if got.Observable != "909663" || got.Attempt != 1 || got.StatusCode != tc.wantStatus || !got.Time.Equal(start) {
+				t.Errorf("Unexpected poll error %+v", got)
+			}
+			if got.Err == nil {
+				t.Errorf("Expected underlying error")
+			}
+
+			poller.stopAll()
+			if _, ok := <-events; ok {
+				t.Errorf("Expected event channel to be closed")
+			}
+			if handled == nil {
+				t.Errorf("Expected error handler to be called")
+			}
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Eid": "909663", "Epr": 1}`)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name string
+		stop func(p *poller, cancel context.CancelFunc) error
+	}{
+		{
+			name: "shutdown",
+			stop: func(p *poller, _ context.CancelFunc) error {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				return p.Shutdown(ctx)
+			},
+		},
+		{
+			name: "listen context cancelled",
+			stop: func(_ *poller, cancel context.CancelFunc) error {
+				cancel()
+				return nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := clock.NewMockClock()
+			poller := New(server.URL+"/", response.LivescoreData{}, WithClock(fc), WithInterval(time.Minute))
+			poller.AddObservable(Observable{Address: "909663"}, Observable{Address: "909664"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := poller.Listen(ctx)
+
+			// one poll is received, the other one is left waiting to be delivered
+			<-events
+			if err := tc.stop(poller, cancel); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// a poll racing the shutdown may still be delivered before the channel closes
+			for range events {
+			}
+			if err, ok := <-poller.Errors(); ok {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestObservablesChangedWhileListening(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Eid": "%s", "Epr": 1}`, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer server.Close()
+
+	poller := New(server.URL+"/", response.LivescoreData{}, WithClock(clock.NewMockClock()), WithInterval(time.Minute))
+	poller.KeepListening(true)
+	events := poller.Listen(context.Background())
+
+	// a newly announced match is polled straight away
+	poller.AddObservable(Observable{Address: "909663"})
+	if got := (<-events).Observable.Address; got != "909663" {
+		t.Errorf("Expected %v got %v", "909663", got)
+	}
+
+	if !poller.RemoveObservable("909663") {
+		t.Errorf("Expected observable to be removed")
+	}
+	waitFor(t, func() bool {
+		return len(poller.Stats()) == 0
+	})
+	if poller.RemoveObservable("909663") {
+		t.Errorf("Expected removing an unknown observable to report false")
+	}
+
+	// updating an observable restarts its polling
+	poller.AddObservable(Observable{Address: "909664"})
+	<-events
+	if !poller.UpdateObservable(Observable{Address: "909664"}) {
+		t.Errorf("Expected observable to be updated")
+	}
+	if got := (<-events).Observable.Address; got != "909664" {
+		t.Errorf("Expected %v got %v", "909664", got)
+	}
+	if poller.UpdateObservable(Observable{Address: "909665"}) {
+		t.Errorf("Expected updating an unknown observable to report false")
+	}
+
+	poller.Shutdown(context.Background())
+	for range events {
+	}
+}
+
+func TestObservableRequestOptions(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		observable Observable
+		wantURL    string
+		wantHeader string
+	}{
+		{
+			name:       "address appended to api url",
+			observable: Observable{Address: "909663"},
+			wantURL:    "/soccer/909663",
+		},
+		{
+			name:       "query params and headers",
+			observable: Observable{Address: "909663", Query: url.Values{"locale": {"en"}}, Header: http.Header{"X-Api-Key": {"secret"}}},
+			wantURL:    "/soccer/909663?locale=en",
+			wantHeader: "secret",
+		},
+		{
+			name:       "url override keeps its own query",
+			observable: Observable{Address: "909663", URL: server.URL + "/basketball/909663?live=1", Query: url.Values{"locale": {"en"}}},
+			wantURL:    "/basketball/909663?live=1&locale=en",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			poller := &poller{apiUrl: server.URL + "/soccer/", responseType: response.LivescoreData{}}
+			if _, err := poller.fetchData(context.Background(), tc.observable); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got.URL.RequestURI() != tc.wantURL {
+				t.Errorf("Expected %v got %v", tc.wantURL, got.URL.RequestURI())
+			}
+			if header := got.Header.Get("X-Api-Key"); header != tc.wantHeader {
+				t.Errorf("Expected %v got %v", tc.wantHeader, header)
+			}
+		})
+	}
+}
+
+func TestObservableInterval(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 15, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Eid": "909663", "Epr": 1}`)
+	}))
+	defer server.Close()
+
+	poller := New(server.URL+"/", response.LivescoreData{}, WithClock(fc), WithInterval(time.Minute))
+	poller.AddObservable(Observable{Address: "909663", Interval: 5 * time.Second})
+	events := poller.Listen(context.Background())
+
+	<-events
+	// polled again well before the poller's default interval
+	for polled := false; !polled; {
+		select {
+		case <-events:
+			polled = true
+		case <-time.After(5 * time.Millisecond):
+			fc.AddTime(5 * time.Second)
+		}
+	}
+	if elapsed := fc.Since(start); elapsed >= time.Minute {
+		t.Errorf("Expected second poll within %v got %v", time.Minute, elapsed)
+	}
+
+	poller.Shutdown(context.Background())
+	for range events {
+	}
+}
+
+func TestRateLimitOnPollerClock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Eid": "%s", "Epr": 1}`, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer server.Close()
+
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 15, 0, 0, 0, time.UTC))
+	poller := New(server.URL+"/", response.LivescoreData{}, WithClock(fc), WithInterval(time.Hour))
+	// one request every 100 seconds
+	poller.SetRateLimit(0.01, 1)
+	poller.AddObservable(Observable{Address: "909663"}, Observable{Address: "909664"})
+	events := poller.Listen(context.Background())
+	<-events
+
+	// the second observable waits for a token on the mock clock
+	select {
+	case <-events:
+		t.Fatal("Expected second poll to wait for the rate limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	fc.AddTime(100 * time.Second)
+	<-events
+
+	poller.Shutdown(context.Background())
+	for range events {
+	}
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+// realClock implements clock.Clock on top of the time package.
+// clock.C wraps copies of time.Timer and time.Ticker values, which the
+// runtime no longer supports since Go 1.23: stopping them can deadlock.
+type realClock struct{}
+
+// RealClock is the clock jobs run on unless given another one
+var RealClock clock.Clock = realClock{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (realClock) Tick(d time.Duration) <-chan time.Time { return time.Tick(d) }
+
+func (realClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return &realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTimer(d time.Duration) clock.Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) clock.Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+type realTimer struct{ *time.Timer }
+
+func (t *realTimer) Chan() <-chan time.Time {
+	return t.C
+}
+
+type realTicker struct{ *time.Ticker }
+
+func (t *realTicker) Chan() <-chan time.Time {
+	return t.C
+}
@@ -0,0 +1,335 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+// schedule calculates the next activation time strictly after the given time.
+// A zero time means the schedule will never fire again.
+type schedule interface {
+	next(t time.Time) time.Time
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// 7 is accepted as an alias for Sunday and folded into 0 once parsed
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// cronSchedule holds every field of a cron expression as a bit set,
+// bit n being set when value n matches.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// Day of month and day of week are OR'ed together when both are restricted
+	domAny, dowAny bool
+}
+
+// parseCron parses a standard 5-field cron expression
+// (minute hour day-of-month month day-of-week) or a 6-field one
+// with a leading seconds field.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+	targets := []struct {
+		field cronField
+		bits  *uint64
+	}{
+		{secondField, &s.second},
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	}
+	for i, target := range targets {
+		if *target.bits, err = target.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", spec, err)
+		}
+	}
+
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny = fields[3] == "*" || fields[3] == "?"
+	s.dowAny = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// parse converts a comma separated list of values, ranges and steps into a bit set
+func (f cronField) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := f.parseRange(part)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func (f cronField) parseRange(expr string) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepExpr)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", stepExpr, f.name)
+		}
+		step = n
+	}
+
+	var start, end int
+	switch {
+	case rangeExpr == "*" || rangeExpr == "?":
+		start, end = f.min, f.max
+	case strings.Contains(rangeExpr, "-"):
+		lo, hi, _ := strings.Cut(rangeExpr, "-")
+		var err error
+		if start, err = f.value(lo); err != nil {
+			return 0, err
+		}
+		if end, err = f.value(hi); err != nil {
+			return 0, err
+		}
+	default:
+		n, err := f.value(rangeExpr)
+		if err != nil {
+			return 0, err
+		}
+		start, end = n, n
+		// "5/15" means starting at 5 every 15 until the end of the range
+		if hasStep {
+			end = f.max
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range %q in %s field", rangeExpr, f.name)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+func (f cronField) value(expr string) (int, error) {
+	if n, ok := f.names[strings.ToUpper(expr)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", expr, f.name)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d] in %s field", n, f.min, f.max, f.name)
+	}
+	return n, nil
+}
+
+func has(bits uint64, n int) bool {
+	return bits&(1<<uint(n)) != 0
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	// Give up if nothing matches within five years, e.g. "0 0 30 2 *"
+	limit := t.Year() + 5
+
+wrap:
+	for t.Year() <= limit {
+		for !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+		for !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+		for !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+		for !has(s.minute, t.Minute()) {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+		for !has(s.second, t.Second()) {
+			t = t.Add(time.Second)
+			if t.Second() == 0 {
+				continue wrap
+			}
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// scheduleTicker implements clock.Ticker delivering ticks at the times
+// produced by a schedule. Like time.Ticker it drops ticks for slow receivers.
+type scheduleTicker struct {
+	schedule schedule
+	c        chan time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+	// guards next
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newScheduleTicker(c clock.Clock, s schedule) *scheduleTicker {
+	t := &scheduleTicker{
+		schedule: s,
+		c:        make(chan time.Time, 1),
+		stop:     make(chan struct{}),
+	}
+
+	// schedules relative to the start, see After, are resolved on the ticker's clock
+	if r, ok := s.(interface{ from(time.Time) schedule }); ok {
+		s = r.from(c.Now())
+	}
+	next := s.next(c.Now())
+	if next.IsZero() {
+		return t
+	}
+	t.next = next
+	// First timer is armed before returning so mocked clocks can be advanced straight away
+	timer := c.NewTimer(next.Sub(c.Now()))
+	go t.run(c, s, next, timer)
+	return t
+}
+
+func (t *scheduleTicker) run(c clock.Clock, s schedule, next time.Time, timer clock.Timer) {
+	for {
+		select {
+		case <-timer.Chan():
+		case <-t.stop:
+			timer.Stop()
+			return
+		}
+
+		fired := next
+		next = s.next(fired)
+		t.mu.Lock()
+		t.next = next
+		t.mu.Unlock()
+
+		if next.IsZero() {
+			t.send(fired)
+			return
+		}
+		timer = c.NewTimer(next.Sub(c.Now()))
+		t.send(fired)
+	}
+}
+
+func (t *scheduleTicker) send(tick time.Time) {
+	select {
+	case t.c <- tick:
+	default:
+	}
+}
+
+// nextTick returns when the ticker fires next, zero if it never will
+func (t *scheduleTicker) nextTick() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.next
+}
+
+// period estimates the time between ticks from the next two, zero if the
+// ticker doesn't fire again
+func (t *scheduleTicker) period() time.Duration {
+	next := t.nextTick()
+	if next.IsZero() {
+		return 0
+	}
+	after := t.schedule.next(next)
+	if after.IsZero() {
+		return 0
+	}
+	return after.Sub(next)
+}
+
+func (t *scheduleTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *scheduleTicker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// Cron creates a job whose ticks follow a cron expression, e.g. "*/5 9-23 * * SAT,SUN".
+// Standard 5-field expressions are supported as well as a leading seconds field.
+// Unlike jobs created with Every, a cron job does not run until its first tick.
+func Cron(spec string) (*Job, error) {
+	s, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	j := newJob(newScheduleTicker(realClock{}, s))
+	j.immediate = false
+	j.spec = spec
+	return j, nil
+}
+
+// MustCron is like Cron but panics if the expression cannot be parsed.
+func MustCron(spec string) *Job {
+	j, err := Cron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return j
+}
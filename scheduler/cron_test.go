@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestCronNextActivation(t *testing.T) {
+	// Saturday
+	start := time.Date(2024, time.March, 2, 8, 58, 30, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "every five minutes during weekend match window before window opens",
+			spec: "*/5 9-23 * * SAT,SUN",
+			from: start,
+			want: time.Date(2024, time.March, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "every five minutes during weekend match window inside window",
+			spec: "*/5 9-23 * * SAT,SUN",
+			from: time.Date(2024, time.March, 2, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.March, 2, 9, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "weekend window rolls over to next weekend",
+			spec: "*/5 9-23 * * SAT,SUN",
+			from: time.Date(2024, time.March, 3, 23, 55, 0, 0, time.UTC),
+			want: time.Date(2024, time.March, 9, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "optional seconds field",
+			spec: "*/10 * * * * *",
+			from: start,
+			want: time.Date(2024, time.March, 2, 8, 58, 40, 0, time.UTC),
+		},
+		{
+			name: "seven is an alias for sunday",
+			spec: "0 12 * * 7",
+			from: start,
+			want: time.Date(2024, time.March, 3, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month and day of week are OR'ed",
+			spec: "0 0 15 * MON",
+			from: start,
+			want: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			spec: "0 0 29 FEB *",
+			from: start,
+			want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "impossible date never fires",
+			spec: "0 0 30 2 *",
+			from: start,
+			want: time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := parseCron(tc.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := s.next(tc.from)
+			if !got.Equal(tc.want) {
+				t.Errorf("Expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCronInvalidExpression(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec string
+	}{
+		{name: "too few fields", spec: "* * * *"},
+		{name: "too many fields", spec: "* * * * * * *"},
+		{name: "minute out of range", spec: "60 * * * *"},
+		{name: "unknown day name", spec: "* * * * FUN"},
+		{name: "inverted range", spec: "* 23-9 * * *"},
+		{name: "zero step", spec: "*/0 * * * *"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Cron(tc.spec); err == nil {
+				t.Errorf("Expected error for %q", tc.spec)
+			}
+		})
+	}
+}
+
+func TestCronJobRunsOnlyOnTicks(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 9, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+
+	s, _ := parseCron("*/5 * * * *")
+	job := newJob(newScheduleTicker(fc, s))
+	job.immediate = false
+
+	runs := make(chan struct{})
+	job.Repeat(2).Do(func() {
+		runs <- struct{}{}
+	})
+
+	select {
+	case <-runs:
+		t.Fatal("Cron job ran before its first tick")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fc.AddTime(5 * time.Minute)
+	<-runs
+	fc.AddTime(5 * time.Minute)
+	<-runs
+	job.Wait()
+}
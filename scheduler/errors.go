@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+var ErrPanic = errors.New("job function panicked")
+
+// JobError describes a failed run of a job
+type JobError struct {
+	// Name the job was registered under, empty for jobs outside of a Scheduler
+	Job string
+	// Run number the error occurred on, starting at 1
+	Attempt int
+	// Retries made within the run before giving up
+	Retries int
+	Err     error
+	// Stack trace of the panic, nil if the function returned an error
+	Stack []byte
+}
+
+func (e *JobError) Error() string {
+	if e.Job == "" {
+		return fmt.Sprintf("job run %d: %v", e.Attempt, e.Err)
+	}
+	return fmt.Sprintf("job %q run %d: %v", e.Job, e.Attempt, e.Err)
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// OnError sets the hook called whenever a run returns an error or panics.
+// Without a hook failures are logged.
+func (j *Job) OnError(fn func(*JobError)) *Job {
+	j.errorHandler = fn
+	return j
+}
+
+// recoverRun turns a panic in the job function into an error
+func recoverRun(err *error, stack *[]byte) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", ErrPanic, r)
+		*stack = debug.Stack()
+	}
+}
+
+func (j *Job) reportError(jobErr *JobError) {
+	if j.errorHandler != nil {
+		j.errorHandler(jobErr)
+		return
+	}
+	slog.Error("Job run failed.", "job", jobErr.Job, "attempt", jobErr.Attempt, "error", jobErr.Err)
+}
@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCycle            = errors.New("dependency cycle")
+	ErrUnknownTask      = errors.New("unknown task")
+	ErrDependencyFailed = errors.New("dependency failed")
+)
+
+// Graph runs tasks in dependency order, a task only runs once all of its
+// dependencies succeeded, or completed for tasks added with AddAfter, and
+// tasks without a dependency between them run concurrently. Graph.Run can be scheduled like any other function:
+//
+//	Every(ticker).DoCtx(graph.Run)
+type Graph struct {
+	mu    sync.Mutex
+	tasks map[string]*task
+	// names in the order tasks were added
+	order []string
+}
+
+type task struct {
+	fn   func(context.Context) error
+	deps []string
+	// runs once its dependencies completed, whether they succeeded or not
+	always bool
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		tasks: make(map[string]*task),
+	}
+}
+
+// Add registers a task running after all tasks it depends on succeeded.
+// Dependencies may be added later, they are resolved when the graph runs.
+func (g *Graph) Add(name string, fn func(context.Context) error, dependsOn ...string) error {
+	return g.add(name, &task{fn: fn, deps: dependsOn})
+}
+
+// AddAfter registers a task running once all tasks it depends on completed,
+// whether they succeeded or not, e.g. a report on the outcome of the others
+func (g *Graph) AddAfter(name string, fn func(context.Context) error, after ...string) error {
+	return g.add(name, &task{fn: fn, deps: after, always: true})
+}
+
+func (g *Graph) add(name string, t *task) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.tasks[name]; ok {
+		return fmt.Errorf("task %q already added", name)
+	}
+
+	g.tasks[name] = t
+	g.order = append(g.order, name)
+	return nil
+}
+
+// Validate checks that every dependency exists and there are no cycles
+func (g *Graph) Validate() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.validate()
+}
+
+func (g *Graph) validate() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(g.tasks))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(path, name), " -> "))
+		}
+
+		marks[name] = visiting
+		for _, dep := range g.tasks[name].deps {
+			if _, ok := g.tasks[dep]; !ok {
+				return fmt.Errorf("task %q depends on %w %q", name, ErrUnknownTask, dep)
+			}
+			if err := visit(dep, append(path[:len(path):len(path)], name)); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		return nil
+	}
+
+	for _, name := range g.order {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run executes the graph once and returns the errors of all failed or
+// skipped tasks joined together. Tasks depending on a failed task are
+// skipped with ErrDependencyFailed, a panicking task fails with ErrPanic.
+// Tasks added by a running task, e.g. one per match found in a fixture
+// list, run in the same run once the tasks before them finished.
+func (g *Graph) Run(ctx context.Context) error {
+	done := make(map[string]chan struct{})
+	results := make(map[string]error)
+	var resultsMu sync.Mutex
+	result := func(dep string) error {
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		return results[dep]
+	}
+
+	var ran []string
+	var runErr error
+	for {
+		// the lock is only held to take the tasks, so tasks can add others
+		batch, err := g.pending(done)
+		if err != nil {
+			runErr = err
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		for _, name := range batch {
+			wg.Add(1)
+			go func(name string, t *task) {
+				defer wg.Done()
+				defer close(done[name])
+
+				err := g.runTask(ctx, t, done, result)
+
+				resultsMu.Lock()
+				results[name] = err
+				resultsMu.Unlock()
+			}(name, g.task(name))
+		}
+		wg.Wait()
+		ran = append(ran, batch...)
+	}
+
+	var errs []error
+	for _, name := range ran {
+		if err := results[name]; err != nil {
+			errs = append(errs, fmt.Errorf("task %q: %w", name, err))
+		}
+	}
+	return errors.Join(append(errs, runErr)...)
+}
+
+// pending validates the graph and returns the tasks that didn't run yet in
+// the order they were added, preparing their done channels
+func (g *Graph) pending(done map[string]chan struct{}) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	var batch []string
+	for _, name := range g.order {
+		if _, ok := done[name]; !ok {
+			done[name] = make(chan struct{})
+			batch = append(batch, name)
+		}
+	}
+	return batch, nil
+}
+
+func (g *Graph) task(name string) *task {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.tasks[name]
+}
+
+// runTask waits for the task's dependencies and runs it if they all
+// succeeded, or regardless of their outcome for tasks added with AddAfter
+func (g *Graph) runTask(ctx context.Context, t *task, done map[string]chan struct{}, result func(string) error) (err error) {
+	for _, dep := range t.deps {
+		<-done[dep]
+		if result(dep) != nil && !t.always {
+			return fmt.Errorf("%w: %s", ErrDependencyFailed, dep)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// the task runs on a goroutine of its own, out of reach of the job's recovery
+	var stack []byte
+	defer recoverRun(&err, &stack)
+	return t.fn(ctx)
+}
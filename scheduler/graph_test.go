@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder notes the order tasks ran in
+type recorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *recorder) task(name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.order = append(r.order, name)
+		return err
+	}
+}
+
+func (r *recorder) index(name string) int {
+	for i, n := range r.order {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestGraphRunsInDependencyOrder(t *testing.T) {
+	r := &recorder{}
+	g := NewGraph()
+
+	g.Add("report", r.task("report", nil), "fixtures", "standings")
+	g.Add("fixtures", r.task("fixtures", nil))
+	g.Add("standings", r.task("standings", nil))
+	g.Add("matches", r.task("matches", nil), "fixtures")
+
+	if err := g.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(r.order) != 4 {
+		t.Fatalf("Expected all 4 tasks to run, got %v", r.order)
+	}
+	if r.index("matches") < r.index("fixtures") {
+		t.Errorf("Expected matches to run after fixtures, got %v", r.order)
+	}
+	if r.index("report") < r.index("fixtures") || r.index("report") < r.index("standings") {
+		t.Errorf("Expected report to run after fixtures and standings, got %v", r.order)
+	}
+}
+
+func TestGraphFailurePropagates(t *testing.T) {
+	r := &recorder{}
+	errBoom := errors.New("boom")
+	g := NewGraph()
+
+	g.Add("fixtures", r.task("fixtures", errBoom))
+	g.Add("matches", r.task("matches", nil), "fixtures")
+	g.Add("report", r.task("report", nil), "matches")
+	g.Add("standings", r.task("standings", nil))
+
+	err := g.Run(context.Background())
+
+	if !errors.Is(err, errBoom) || !errors.Is(err, ErrDependencyFailed) {
+		t.Errorf("Expected %v and %v got %v", errBoom, ErrDependencyFailed, err)
+	}
+	if r.index("matches") != -1 || r.index("report") != -1 {
+		t.Errorf("Expected dependents of failed task to be skipped, got %v", r.order)
+	}
+	if r.index("standings") == -1 {
+		t.Errorf("Expected independent task to run, got %v", r.order)
+	}
+}
+
+func TestGraphValidation(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+
+	cyclic := NewGraph()
+	cyclic.Add("a", noop, "c")
+	cyclic.Add("b", noop, "a")
+	cyclic.Add("c", noop, "b")
+
+	if err := cyclic.Validate(); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected %v got %v", ErrCycle, err)
+	}
+	if err := cyclic.Run(context.Background()); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected cyclic graph not to run, got %v", err)
+	}
+
+	unknown := NewGraph()
+	unknown.Add("a", noop, "missing")
+	if err := unknown.Validate(); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("Expected %v got %v", ErrUnknownTask, err)
+	}
+
+	if err := unknown.Add("a", noop); err == nil {
+		t.Errorf("Expected error adding duplicate task")
+	}
+}
+
+func TestGraphScheduledAsJob(t *testing.T) {
+	r := &recorder{}
+	g := NewGraph()
+	g.Add("fixtures", r.task("fixtures", nil))
+	g.Add("matches", r.task("matches", nil), "fixtures")
+
+	ticker := newManualTicker()
+	job := Every(ticker).Repeat(2).DoCtx(g.Run)
+	ticker.tick()
+
+	select {
+	case <-job.done:
+	case <-time.After(time.Second):
+		t.Fatal("Graph job did not finish")
+	}
+
+	want := []string{"fixtures", "matches", "fixtures", "matches"}
+	if len(r.order) != len(want) {
+		t.Fatalf("Expected %v got %v", want, r.order)
+	}
+	for i := range want {
+		if r.order[i] != want[i] {
+			t.Errorf("Expected %v got %v", want, r.order)
+		}
+	}
+}
+
+func TestGraphCompletionDependency(t *testing.T) {
+	r := &recorder{}
+	errBoom := errors.New("boom")
+	g := NewGraph()
+
+	g.Add("fixtures", r.task("fixtures", errBoom))
+	g.Add("standings", r.task("standings", nil))
+	g.AddAfter("report", r.task("report", nil), "fixtures", "standings")
+
+	if err := g.Run(context.Background()); !errors.Is(err, errBoom) || errors.Is(err, ErrDependencyFailed) {
+		t.Errorf("Expected only %v got %v", errBoom, err)
+	}
+	if r.index("report") < r.index("fixtures") || r.index("report") < r.index("standings") {
+		t.Errorf("Expected report to run after fixtures and standings, got %v", r.order)
+	}
+}
+
+func TestGraphTaskPanicIsRecovered(t *testing.T) {
+	r := &recorder{}
+	g := NewGraph()
+
+	g.Add("fixtures", func(context.Context) error { panic("boom") })
+	g.Add("matches", r.task("matches", nil), "fixtures")
+	g.Add("standings", r.task("standings", nil))
+
+	err := g.Run(context.Background())
+
+	if !errors.Is(err, ErrPanic) || !errors.Is(err, ErrDependencyFailed) {
+		t.Errorf("Expected %v and %v got %v", ErrPanic, ErrDependencyFailed, err)
+	}
+	if r.index("matches") != -1 || r.index("standings") == -1 {
+		t.Errorf("Expected only the independent task to run, got %v", r.order)
+	}
+}
+
+func TestGraphTasksAddedWhileRunning(t *testing.T) {
+	r := &recorder{}
+	g := NewGraph()
+
+	// a match is polled for every fixture found
+	g.Add("fixtures", func(ctx context.Context) error {
+		for _, match := range []string{"909663", "909664"} {
+			if err := g.Add(match, r.task(match, nil), "fixtures"); err != nil {
+				return err
+			}
+		}
+		return r.task("fixtures", nil)(ctx)
+	})
+
+	if err := g.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r.order) != 3 || r.order[0] != "fixtures" {
+		t.Errorf("Expected fixtures followed by both matches, got %v", r.order)
+	}
+}
@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"time"
+)
+
+type hooks struct {
+	before   func()
+	after    func(time.Duration, error)
+	complete func()
+}
+
+// BeforeRun sets a callback called before every run
+func (j *Job) BeforeRun(fn func()) *Job {
+	j.hooks.before = fn
+	return j
+}
+
+// AfterRun sets a callback called after every run with its duration,
+// including any retries, and the error the run failed with
+func (j *Job) AfterRun(fn func(time.Duration, error)) *Job {
+	j.hooks.after = fn
+	return j
+}
+
+// OnComplete sets a callback called once the job finishes, either because
+// its repeats are exhausted or it was cancelled. In-flight runs complete first.
+func (j *Job) OnComplete(fn func()) *Job {
+	j.hooks.complete = fn
+	return j
+}
+
+// perform executes a single run surrounded by the before and after hooks,
+// returns false if the run was skipped because the job stopped or another
+// instance holds its lease
+func (j *Job) perform(run int) bool {
+	// the job was stopped while the run was queued for a worker
+	if j.Ctx.Err() != nil {
+		return false
+	}
+
+	ctx, ok, release := j.acquire()
+	if !ok {
+		return false
+	}
+	defer release()
+	if !j.throttle() {
+		return false
+	}
+
+	if j.hooks.before != nil {
+		j.hooks.before()
+	}
+
+	started := j.clock.Now()
+	j.recordStart(started)
+	err := j.execute(ctx, run)
+	duration := j.clock.Since(started)
+	j.recordEnd(started, duration, err)
+	j.persist()
+
+	if j.hooks.after != nil {
+		j.hooks.after(duration, err)
+	}
+	return true
+}
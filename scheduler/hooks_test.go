@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestJobLifecycleHooks(t *testing.T) {
+	ticker := newManualTicker()
+	errBoom := errors.New("boom")
+	var events []string
+
+	job := Every(ticker).Repeat(2).BeforeRun(func() {
+		events = append(events, "before")
+	}).AfterRun(func(d time.Duration, err error) {
+		events = append(events, "after: "+errString(err))
+	}).OnComplete(func() {
+		events = append(events, "complete")
+	}).OnError(func(*JobError) {})
+
+	runs := 0
+	job.DoCtx(func(ctx context.Context) error {
+		runs++
+		events = append(events, "run")
+		if runs == 2 {
+			return errBoom
+		}
+		return nil
+	})
+
+	ticker.tick()
+	job.Wait()
+
+	want := []string{"before", "run", "after: <nil>", "before", "run", "after: boom", "complete"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected %v got %v", want, events)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func TestJobOnCompleteWhenCancelled(t *testing.T) {
+	fc := clock.NewMockClock()
+	completed := 0
+
+	job := Every(fc.NewTicker(time.Second)).OnComplete(func() {
+		completed++
+	}).DoFunc(func() {})
+
+	job.Stop()
+	job.Stop()
+	job.Wait()
+
+	if completed != 1 {
+		t.Errorf("Expected OnComplete to be called %d time, got %d", 1, completed)
+	}
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type jitter struct {
+	fraction float64
+	min, max time.Duration
+}
+
+// Jitter spreads runs by ±fraction of the job's interval, so jobs ticking
+// together spread out. Runs can't start before their tick, so the spread is
+// centred on fraction of the interval after it.
+func (j *Job) Jitter(fraction float64) *Job {
+	j.jitter = jitter{fraction: fraction}
+	return j
+}
+
+// JitterRange offsets every run by a random amount between min and max,
+// shifted by -min when min is negative for the same reason as Jitter
+func (j *Job) JitterRange(min, max time.Duration) *Job {
+	j.jitter = jitter{min: min, max: max}
+	return j
+}
+
+// Seed makes the job's randomness, its jitter and retry backoff, reproducible
+func (j *Job) Seed(seed int64) *Job {
+	r := &lockedRand{r: rand.New(rand.NewSource(seed))}
+	j.random = r.Float64
+	return j
+}
+
+// lockedRand is a seeded source safe for concurrent runs
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+// jitterDelay draws the delay of the next run
+func (j *Job) jitterDelay() time.Duration {
+	lo, hi := j.jitter.min, j.jitter.max
+	if j.jitter.fraction > 0 {
+		j.mu.Lock()
+		interval := j.every
+		if interval == 0 {
+			interval = j.period
+		}
+		ticker, scheduled := j.interval.(*scheduleTicker)
+		j.mu.Unlock()
+		if interval == 0 && scheduled {
+			interval = ticker.period()
+		}
+		spread := time.Duration(float64(interval) * j.jitter.fraction)
+		lo, hi = -spread, spread
+	}
+
+	if lo < 0 {
+		lo, hi = 0, hi-lo
+	}
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(j.random()*float64(hi-lo))
+}
+
+// submitJittered hands the run over once its jitter elapsed
+func (j *Job) submitJittered(fn func()) {
+	wait := j.jitterDelay()
+	if wait <= 0 {
+		j.submit(fn)
+		return
+	}
+
+	go func() {
+		// a run of a stopped job is skipped by perform
+		j.sleep(wait)
+		j.submit(fn)
+	}()
+}
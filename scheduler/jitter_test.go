@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestJitterDelay(t *testing.T) {
+	testCases := []struct {
+		name     string
+		job      *Job
+		min, max time.Duration
+	}{
+		{
+			name: "fixed range",
+			job:  Every(newManualTicker()).JitterRange(time.Second, 3*time.Second),
+			min:  time.Second,
+			max:  3 * time.Second,
+		},
+		{
+			name: "fraction of interval",
+			job:  Every(newManualTicker()).AlignTo(time.Minute).Jitter(0.1),
+			min:  0,
+			max:  12 * time.Second,
+		},
+		{
+			name: "fraction of cron interval before any tick",
+			job:  MustCron("*/5 * * * *").Jitter(0.1),
+			min:  0,
+			max:  time.Minute,
+		},
+		{
+			name: "negative bounds are shifted",
+			job:  Every(newManualTicker()).JitterRange(-time.Second, time.Second),
+			min:  0,
+			max:  2 * time.Second,
+		},
+		{
+			name: "fraction of declared interval",
+			job:  Every(newManualTicker()).Interval(time.Minute).Jitter(0.1),
+			min:  0,
+			max:  12 * time.Second,
+		},
+		{
+			name: "no jitter",
+			job:  Every(newManualTicker()),
+			min:  0,
+			max:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := tc.job.jitterDelay(); got < tc.min || got > tc.max {
+					t.Fatalf("Expected delay between %v and %v got %v", tc.min, tc.max, got)
+				}
+			}
+		})
+	}
+}
+
+func TestJitterFallsBackToScheduleInterval(t *testing.T) {
+	job := MustCron("*/5 * * * *").Jitter(1).Seed(42)
+	if got := job.jitterDelay(); got == 0 {
+		t.Errorf("Expected jitter before the first tick")
+	}
+}
+
+func TestJitterIsReproducibleWithSeed(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		job := Every(newManualTicker()).JitterRange(0, time.Minute).Seed(seed)
+		var got []time.Duration
+		for i := 0; i < 5; i++ {
+			got = append(got, job.jitterDelay())
+		}
+		return got
+	}
+
+	if first, second := delays(42), delays(42); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v got %v", first, second)
+	}
+	if first, other := delays(42), delays(7); reflect.DeepEqual(first, other) {
+		t.Errorf("Expected different seeds to give different delays, got %v", first)
+	}
+}
+
+func TestJobJitterDelaysRun(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	runs := make(chan time.Time)
+
+	job := Every(newManualTicker()).Clock(fc).JitterRange(10*time.Second, 10*time.Second).Repeat(1)
+	job.DoFunc(func() {
+		runs <- fc.Now()
+	})
+
+	// let the run start waiting out its jitter
+	time.Sleep(50 * time.Millisecond)
+	fc.AddTime(9 * time.Second)
+	select {
+	case <-runs:
+		t.Fatal("Unexpected run before jitter elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fc.AddTime(time.Second)
+	if got, want := <-runs, start.Add(10*time.Second); !got.Equal(want) {
+		t.Errorf("Expected run at %v got %v", want, got)
+	}
+	job.Wait()
+}
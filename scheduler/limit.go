@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+// Limiter is a token bucket capping how often the jobs sharing it run.
+// Runs waiting for a token are let through in the order they arrived.
+type Limiter struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	// tokens added per second
+	rate  float64
+	burst float64
+	// negative while runs are queued for tokens not yet added
+	tokens float64
+	last   time.Time
+}
+
+// LimiterOption configures a limiter created with NewLimiter
+type LimiterOption func(*Limiter)
+
+// WithLimiterClock measures the rate on the given clock, e.g. the one the
+// scheduler was given with WithClock
+func WithLimiterClock(c clock.Clock) LimiterOption {
+	return func(l *Limiter) {
+		l.clock = c
+	}
+}
+
+// NewLimiter allows perSecond runs on average and up to burst runs at once.
+// A rate of zero or less lets the burst through and blocks every run after it.
+func NewLimiter(perSecond float64, burst int, opts ...LimiterOption) *Limiter {
+	burst = max(burst, 1)
+	l := &Limiter{
+		clock:  realClock{},
+		rate:   max(perSecond, 0),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Wait blocks until a token is available, returns an error if ctx is done first
+func (l *Limiter) Wait(ctx context.Context) error {
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
+
+	// without a rate the token never comes, only ctx ends the wait
+	var fire <-chan time.Time
+	if wait != never {
+		timer := l.clock.NewTimer(wait)
+		defer timer.Stop()
+		fire = timer.Chan()
+	}
+
+	select {
+	case <-fire:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// never is the wait for a token of a limiter without a rate
+const never = time.Duration(math.MaxInt64)
+
+// reserve takes a token, going into debt if there is none, and returns how
+// long to wait until the debt is paid off. Taking tokens in turn under the
+// lock is what keeps waiting runs in order.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	if l.rate == 0 {
+		return never
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns the token of a run that stopped waiting
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens = min(l.tokens+1, l.burst)
+}
+
+func (l *Limiter) refill() {
+	now := l.clock.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	}
+	l.last = now
+}
+
+// Limit makes the job take a token from the limiter before every attempt.
+// Jobs sharing a limiter never run more often than it allows in total.
+func (j *Job) Limit(l *Limiter) *Job {
+	j.limiter = l
+	return j
+}
+
+// UseLimiter applies the limiter to jobs added from now on that don't have one of their own
+func (s *scheduler) UseLimiter(l *Limiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiter = l
+}
+
+// throttle waits for the job's limiter, returns false if the job was stopped meanwhile
+func (j *Job) throttle() bool {
+	j.mu.Lock()
+	l := j.limiter
+	j.mu.Unlock()
+
+	if l == nil {
+		return true
+	}
+	return l.Wait(j.Ctx) == nil
+}
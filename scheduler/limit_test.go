@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestLimiterReservesTokensInOrder(t *testing.T) {
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	l := NewLimiter(2, 2, WithLimiterClock(fc))
+
+	testCases := []struct {
+		advance time.Duration
+		want    time.Duration
+	}{
+		{advance: 0, want: 0},
+		{advance: 0, want: 0},
+		{advance: 0, want: 500 * time.Millisecond},
+		{advance: 0, want: time.Second},
+		{advance: time.Second, want: 500 * time.Millisecond},
+		{advance: 5 * time.Second, want: 0},
+	}
+
+	for i, tc := range testCases {
+		fc.AddTime(tc.advance)
+		if got := l.reserve(); got != tc.want {
+			t.Errorf("Reservation %d: expected %v got %v", i, tc.want, got)
+		}
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	l := NewLimiter(1, 1)
+	l.clock = fc
+	l.reserve()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("Expected %v got %v", context.Canceled, err)
+	}
+
+	// the cancelled run gave its token back
+	if got := l.reserve(); got != time.Second {
+		t.Errorf("Expected %v got %v", time.Second, got)
+	}
+}
+
+func TestLimiterWithoutRateBlocks(t *testing.T) {
+	l := NewLimiter(0, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSchedulerLimitsJobs(t *testing.T) {
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(1, 1)
+	limiter.clock = fc
+
+	s := New()
+	s.UseLimiter(limiter)
+
+	runs := make(chan struct{})
+	record := func() {
+		runs <- struct{}{}
+	}
+	first, second := Every(newManualTicker()), Every(newManualTicker())
+	s.Add("first", first)
+	s.Add("second", second)
+
+	first.DoFunc(record)
+	<-runs
+	second.DoFunc(record)
+	expectNoRun(t, runs)
+
+	fc.AddTime(time.Second)
+	<-runs
+
+	s.StopAll()
+	s.Wait()
+}
@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+// Locker grants a lease on a job to one owner at a time, so that a job
+// scheduled by several instances only runs on one of them. A lease that is
+// not renewed before it expires can be taken over by another owner.
+type Locker interface {
+	// Lock acquires the lease for owner, or extends it if owner already holds it.
+	// Reports false if another owner holds a lease that has not expired.
+	Lock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Unlock gives up the lease if owner holds it
+	Unlock(ctx context.Context, name, owner string) error
+}
+
+type lease struct {
+	locker Locker
+	owner  string
+	ttl    time.Duration
+}
+
+// UseLocker makes jobs added from now on run only while this scheduler holds
+// their lease. The lease is kept between runs and renewed while a run is in
+// progress, ttl should be longer than the interval of the jobs so the
+// instance holding it stays in charge. Runs skipped for want of the lease do
+// not count towards the job's repeats.
+func (s *scheduler) UseLocker(locker Locker, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lease = lease{locker: locker, owner: newOwner(), ttl: ttl}
+}
+
+// newOwner identifies the scheduler instance holding a lease
+func newOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%08x", host, os.Getpid(), rand.Uint32())
+}
+
+// acquire reports if the job may run, renewing its lease until the returned
+// function is called. The returned context of the run is cancelled if the
+// lease is lost meanwhile, e.g. taken over by another instance after renewals
+// failed, so two instances never run the job at once.
+func (j *Job) acquire() (context.Context, bool, func()) {
+	j.mu.Lock()
+	l := j.lease
+	name := j.name
+	j.mu.Unlock()
+
+	if l.locker == nil {
+		return j.Ctx, true, func() {}
+	}
+
+	ok, err := l.locker.Lock(j.Ctx, name, l.owner, l.ttl)
+	if err != nil {
+		slog.Warn("Acquiring job lock failed.", "job", name, "error", err)
+		return nil, false, nil
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	if l.ttl <= 0 {
+		return j.Ctx, true, func() {}
+	}
+
+	// keep the lease while the run takes longer than its ttl
+	ctx, cancel := context.WithCancel(j.Ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := j.clock.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.Chan():
+				ok, err := l.locker.Lock(ctx, name, l.owner, l.ttl)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil || !ok {
+					slog.Warn("Job lock lost, cancelling the run.", "job", name, "error", err)
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ctx, true, func() {
+		cancel()
+		<-renewed
+	}
+}
+
+// unlock gives up the job's lease once it finishes
+func (j *Job) unlock() {
+	j.mu.Lock()
+	l := j.lease
+	name := j.name
+	j.mu.Unlock()
+
+	if l.locker == nil {
+		return
+	}
+	if err := l.locker.Unlock(context.Background(), name, l.owner); err != nil {
+		slog.Warn("Releasing job lock failed.", "job", name, "error", err)
+	}
+}
+
+// FileLocker keeps leases in files of a directory, which may be on a volume
+// shared by several hosts
+type FileLocker struct {
+	dir   string
+	clock clock.Clock
+	// age after which a guard file left behind by a crashed owner is removed
+	staleGuard time.Duration
+}
+
+type fileLease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLockerOption configures a locker created with NewFileLocker
+type FileLockerOption func(*FileLocker)
+
+// WithLockerClock times leases on the given clock, e.g. the one the scheduler
+// was given with WithClock
+func WithLockerClock(c clock.Clock) FileLockerOption {
+	return func(l *FileLocker) {
+		l.clock = c
+	}
+}
+
+// NewFileLocker creates a locker keeping its leases in dir
+func NewFileLocker(dir string, opts ...FileLockerOption) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	l := &FileLocker{
+		dir:        dir,
+		clock:      realClock{},
+		staleGuard: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+func (l *FileLocker) Lock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	path := l.path(name)
+	unguard, err := l.guard(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	defer unguard()
+
+	current, err := readLease(path)
+	if err != nil {
+		return false, err
+	}
+	now := l.clock.Now()
+	if current.Owner != "" && current.Owner != owner && now.Before(current.Expires) {
+		return false, nil
+	}
+
+	data, err := json.Marshal(fileLease{Owner: owner, Expires: now.Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *FileLocker) Unlock(ctx context.Context, name, owner string) error {
+	path := l.path(name)
+	unguard, err := l.guard(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unguard()
+
+	current, err := readLease(path)
+	if err != nil || current.Owner != owner {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (l *FileLocker) path(name string) string {
+	return filepath.Join(l.dir, url.PathEscape(name)+".lease")
+}
+
+// guard serialises access to a lease file between processes, exclusive
+// creation of the guard file is atomic on local and network file systems
+func (l *FileLocker) guard(ctx context.Context, path string) (func(), error) {
+	guard := path + ".guard"
+	for {
+		f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			// dated on the locker's clock so its age is measured on the same clock
+			now := l.clock.Now()
+			os.Chtimes(guard, now, now)
+			return func() { os.Remove(guard) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(guard); err == nil && l.clock.Since(info.ModTime()) > l.staleGuard {
+			removeStale(guard, info)
+			continue
+		}
+
+		// contention is waited out in real time, the locker's clock only dates leases
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// removeStale removes the guard left behind by a crashed owner. The guard is
+// moved aside first, so one created meanwhile by another owner is never
+// removed: it is put back if it isn't the stale one.
+func removeStale(guard string, stale fs.FileInfo) {
+	aside := fmt.Sprintf("%s.%08x", guard, rand.Uint32())
+	if err := os.Rename(guard, aside); err != nil {
+		return
+	}
+	defer os.Remove(aside)
+
+	// inodes are reused, the modification time tells a new guard apart
+	if info, err := os.Stat(aside); err == nil && (!os.SameFile(info, stale) || !info.ModTime().Equal(stale.ModTime())) {
+		os.Link(aside, guard)
+	}
+}
+
+// readLease returns the lease stored at path, the zero lease if there is none
+func readLease(path string) (fileLease, error) {
+	var current fileLease
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return current, nil
+	}
+	if err != nil {
+		return current, err
+	}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return current, fmt.Errorf("lease %s: %w", path, err)
+	}
+	return current, nil
+}
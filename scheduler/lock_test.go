@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestFileLockerLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	locker, err := NewFileLocker(t.TempDir(), WithLockerClock(fc))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	steps := []struct {
+		name    string
+		owner   string
+		advance time.Duration
+		want    bool
+	}{
+		{name: "first owner acquires", owner: "a", want: true},
+		{name: "second owner is refused", owner: "b", advance: 30 * time.Second, want: false},
+		{name: "first owner renews", owner: "a", want: true},
+		{name: "lease still held after renewal", owner: "b", advance: 45 * time.Second, want: false},
+		{name: "second owner takes over expired lease", owner: "b", advance: 20 * time.Second, want: true},
+		{name: "first owner is refused", owner: "a", want: false},
+	}
+
+	for _, step := range steps {
+		fc.AddTime(step.advance)
+		got, err := locker.Lock(ctx, "909663", step.owner, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: expected %v got %v", step.name, step.want, got)
+		}
+	}
+
+	// only the owner can give up the lease
+	locker.Unlock(ctx, "909663", "a")
+	if ok, _ := locker.Lock(ctx, "909663", "a", time.Minute); ok {
+		t.Errorf("Expected lease to be kept by its owner")
+	}
+	locker.Unlock(ctx, "909663", "b")
+	if ok, _ := locker.Lock(ctx, "909663", "a", time.Minute); !ok {
+		t.Errorf("Expected released lease to be acquired")
+	}
+}
+
+func TestFileLockerContentionOnMockClock(t *testing.T) {
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	locker, err := NewFileLocker(t.TempDir(), WithLockerClock(fc))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// replicas contending for the guard don't wait for the mock clock to move
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	granted := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			ok, err := locker.Lock(ctx, "909663", owner, time.Minute)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if ok {
+				granted <- owner
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+	close(granted)
+
+	if n := len(granted); n != 1 {
+		t.Errorf("Expected the lease granted once got %d", n)
+	}
+}
+
+func TestFileLockerStaleGuard(t *testing.T) {
+	dir := t.TempDir()
+	locker, err := NewFileLocker(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	guard := locker.path("909663") + ".guard"
+
+	// left behind by a crashed owner
+	os.WriteFile(guard, nil, 0o644)
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(guard, old, old)
+	stale, _ := os.Stat(guard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if ok, err := locker.Lock(ctx, "909663", "a", time.Minute); !ok || err != nil {
+		t.Fatalf("Expected stale guard to be removed, got %v %v", ok, err)
+	}
+
+	// a guard created by another owner since it was found stale is kept
+	os.WriteFile(guard, nil, 0o644)
+	removeStale(guard, stale)
+	if _, err := os.Stat(guard); err != nil {
+		t.Errorf("Expected fresh guard to be kept, got %v", err)
+	}
+	if entries, _ := filepath.Glob(guard + ".*"); len(entries) != 0 {
+		t.Errorf("Expected no guard moved aside to be left, got %v", entries)
+	}
+}
+
+func TestSchedulerRunsJobOnLeaseHolderOnly(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	primary, standby := New(), New()
+	primary.UseLocker(locker, time.Minute)
+	standby.UseLocker(locker, time.Minute)
+
+	primaryRuns, standbyRuns := make(chan struct{}), make(chan struct{})
+	primaryJob, standbyJob := Every(newManualTicker()), Every(newManualTicker())
+	primary.Add("match", primaryJob)
+	standby.Add("match", standbyJob)
+
+	primaryJob.DoFunc(func() {
+		primaryRuns <- struct{}{}
+	})
+	<-primaryRuns
+
+	standbyJob.Repeat(1).DoFunc(func() {
+		standbyRuns <- struct{}{}
+	})
+	expectNoRun(t, standbyRuns)
+	if runs := standbyJob.Stats().Runs; runs != 0 {
+		t.Errorf("Expected %d runs got %d", 0, runs)
+	}
+
+	// the standby takes over once the primary gives up the lease
+	primaryJob.Stop()
+	primaryJob.Wait()
+	standbyJob.interval.(*manualTicker).tick()
+	<-standbyRuns
+
+	standbyJob.Wait()
+	if state := standbyJob.State(); state != Finished {
+		t.Errorf("Expected %v got %v", Finished, state)
+	}
+}
+
+// stubLocker grants the lease until another owner takes it over
+type stubLocker struct {
+	mu        sync.Mutex
+	takenOver bool
+}
+
+func (l *stubLocker) Lock(context.Context, string, string, time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.takenOver, nil
+}
+
+func (l *stubLocker) Unlock(context.Context, string, string) error {
+	return nil
+}
+
+func (l *stubLocker) takeOver() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.takenOver = true
+}
+
+func TestRunCancelledWhenLeaseIsLost(t *testing.T) {
+	fc := clock.NewMockClock(time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC))
+	locker := &stubLocker{}
+	s := New(WithClock(fc))
+	s.UseLocker(locker, 3*time.Second)
+
+	started, cancelled := make(chan struct{}), make(chan error)
+	job := Every(fc.NewTicker(time.Minute))
+	s.Add("match", job)
+	job.DoCtx(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+	<-started
+
+	// the next renewal finds the lease taken over by another instance
+	locker.takeOver()
+	for done := false; !done; {
+		select {
+		case err := <-cancelled:
+			if err == nil {
+				t.Errorf("Expected run context to be cancelled")
+			}
+			done = true
+		case <-time.After(5 * time.Millisecond):
+			fc.AddTime(time.Second)
+		}
+	}
+
+	if job.Ctx.Err() != nil {
+		t.Errorf("Expected job to keep running")
+	}
+	job.Stop()
+	job.Wait()
+}
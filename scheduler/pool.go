@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Priority of a job's runs when they wait for a worker, see UseWorkers.
+// Runs of higher priority go first, runs of equal priority in the order they were queued.
+func (j *Job) Priority(priority int) *Job {
+	j.priority = priority
+	return j
+}
+
+// UseWorkers caps the runs in progress across jobs added from now on.
+// Runs over the cap are queued by job priority.
+func (s *scheduler) UseWorkers(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pool = newPool(n)
+}
+
+// pool runs queued functions, at most size at a time
+type pool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	queue   runQueue
+	// breaks ties between runs of equal priority
+	seq uint64
+}
+
+func newPool(size int) *pool {
+	return &pool{size: max(size, 1)}
+}
+
+func (p *pool) submit(priority int, fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	heap.Push(&p.queue, &queuedRun{priority: priority, seq: p.seq, fn: fn})
+	p.seq++
+	p.dispatch()
+}
+
+// dispatch starts queued runs while there are free workers, callers hold mu
+func (p *pool) dispatch() {
+	for p.running < p.size && p.queue.Len() > 0 {
+		run := heap.Pop(&p.queue).(*queuedRun)
+		p.running++
+
+		go func() {
+			run.fn()
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.running--
+			p.dispatch()
+		}()
+	}
+}
+
+type queuedRun struct {
+	priority int
+	seq      uint64
+	fn       func()
+}
+
+// runQueue implements heap.Interface, highest priority first
+type runQueue []*queuedRun
+
+func (q runQueue) Len() int { return len(q) }
+
+func (q runQueue) Less(i, k int) bool {
+	if q[i].priority != q[k].priority {
+		return q[i].priority > q[k].priority
+	}
+	return q[i].seq < q[k].seq
+}
+
+func (q runQueue) Swap(i, k int) { q[i], q[k] = q[k], q[i] }
+
+func (q *runQueue) Push(x any) { *q = append(*q, x.(*queuedRun)) }
+
+func (q *runQueue) Pop() any {
+	old := *q
+	run := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return run
+}
+
+// submit hands the run to the job's pool, or starts it straight away without one
+func (j *Job) submit(fn func()) {
+	j.mu.Lock()
+	p := j.pool
+	j.mu.Unlock()
+
+	if p == nil {
+		go fn()
+		return
+	}
+	p.submit(j.priority, fn)
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPoolRunsByPriority(t *testing.T) {
+	p := newPool(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	p.submit(0, blockingRun(started, release))
+	<-started
+
+	order := make(chan string)
+	for _, run := range []struct {
+		name     string
+		priority int
+	}{
+		{"pre-match 1", 0},
+		{"live 1", 10},
+		{"pre-match 2", 0},
+		{"live 2", 10},
+	} {
+		p.submit(run.priority, func() {
+			order <- run.name
+		})
+	}
+	close(release)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, <-order)
+	}
+	want := []string{"live 1", "live 2", "pre-match 1", "pre-match 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v got %v", want, got)
+	}
+}
+
+func TestSchedulerWorkersPrioritiseJobs(t *testing.T) {
+	s := New()
+	s.UseWorkers(1)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	blocker := Every(newManualTicker()).Repeat(1)
+	s.Add("blocker", blocker)
+	blocker.DoFunc(blockingRun(started, release))
+	<-started
+
+	order := make(chan string)
+	prematch := Every(newManualTicker()).Repeat(1)
+	live := Every(newManualTicker()).Repeat(1).Priority(10)
+	s.Add("prematch", prematch)
+	s.Add("live", live)
+	prematch.DoFunc(func() { order <- "prematch" })
+	live.DoFunc(func() { order <- "live" })
+
+	// let both runs queue up for the only worker
+	time.Sleep(50 * time.Millisecond)
+
+	close(release)
+	if first := <-order; first != "live" {
+		t.Errorf("Expected %v got %v", "live", first)
+	}
+	<-order
+	s.Wait()
+}
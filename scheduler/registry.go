@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mixer/clock"
+)
+
+var (
+	ErrJobExists  = errors.New("job with this name is already scheduled")
+	ErrJobStarted = errors.New("job already started on the real clock")
+)
+
+type scheduler struct {
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	running sync.WaitGroup
+	store   JobStore
+	catchUp CatchUp
+	lease   lease
+	limiter *Limiter
+	pool    *pool
+	clock   clock.Clock
+}
+
+// Option configures a scheduler created with New
+type Option func(*scheduler)
+
+// WithClock runs jobs added to the scheduler on the given clock, unless they
+// were given one of their own with Job.Clock. Tickers passed to Every should
+// come from the same clock, and jobs must be added before Do.
+func WithClock(c clock.Clock) Option {
+	return func(s *scheduler) {
+		s.clock = c
+	}
+}
+
+// New creates a Scheduler that owns the jobs registered with it.
+// Jobs are removed from the scheduler once they finish.
+func New(opts ...Option) *scheduler {
+	s := &scheduler{
+		jobs: make(map[string]*Job),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add registers the job under the given name. A name can only be reused
+// once the job previously registered under it has finished.
+func (s *scheduler) Add(name string, job *Job) error {
+	if job == nil {
+		return fmt.Errorf("job %q: cannot schedule nil job", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.jobs[name]; ok && existing.Ctx.Err() == nil {
+		return fmt.Errorf("job %q: %w", name, ErrJobExists)
+	}
+
+	// a running job can't move over to the scheduler's clock
+	if _, ok := job.clock.(realClock); ok && s.clock != nil {
+		if job.State() != Idle {
+			return fmt.Errorf("job %q: %w", name, ErrJobStarted)
+		}
+		job.Clock(s.clock)
+	}
+	if err := s.restore(name, job); err != nil {
+		return fmt.Errorf("job %q: %w", name, err)
+	}
+
+	job.mu.Lock()
+	job.name = name
+	job.store = s.store
+	job.lease = s.lease
+	job.pool = s.pool
+	if job.limiter == nil {
+		job.limiter = s.limiter
+	}
+	job.mu.Unlock()
+	s.jobs[name] = job
+	s.running.Add(1)
+	go s.release(name, job)
+	return nil
+}
+
+// restore resumes a job that has not started yet from its stored record
+func (s *scheduler) restore(name string, job *Job) error {
+	if s.store == nil || job.State() != Idle {
+		return nil
+	}
+
+	record, ok, err := s.store.Load(name)
+	if err != nil || !ok {
+		return err
+	}
+	// left behind by a job that finished, the new job starts afresh
+	if record.Remaining == 0 {
+		return s.store.Delete(name)
+	}
+	job.restore(record, s.catchUp, job.clock.Now())
+	return nil
+}
+
+// release removes the job from the scheduler once it finishes
+func (s *scheduler) release(name string, job *Job) {
+	defer s.running.Done()
+	job.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs[name] == job {
+		delete(s.jobs, name)
+	}
+}
+
+// Job looks up a scheduled job by name
+func (s *scheduler) Job(name string) (*Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[name]
+	return j, ok
+}
+
+// Jobs returns the sorted names of all scheduled jobs
+func (s *scheduler) Jobs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stop cancels the named job, returns false if no such job is scheduled.
+// Unlike StopAll, the job's stored record is deleted.
+func (s *scheduler) Stop(name string) bool {
+	j, ok := s.Job(name)
+	if !ok {
+		return false
+	}
+	j.mu.Lock()
+	j.removed = true
+	j.mu.Unlock()
+	j.Stop()
+	return true
+}
+
+func (s *scheduler) StopAll() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, j := range s.jobs {
+		j.Stop()
+	}
+}
+
+// Blocking until all scheduled jobs finish
+func (s *scheduler) Wait() {
+	s.running.Wait()
+}
@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestRegistryAddAndLookup(t *testing.T) {
+	fc := clock.NewMockClock()
+	s := New()
+
+	first := Every(fc.NewTicker(time.Second))
+	second := Every(fc.NewTicker(time.Second))
+
+	if err := s.Add("second", second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Add("first", first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got, want := s.Jobs(), []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v got %v", want, got)
+	}
+
+	j, ok := s.Job("first")
+	if !ok || j != first {
+		t.Errorf("Expected to find job registered as first")
+	}
+	if j.Name() != "first" {
+		t.Errorf("Expected job name %s got %s", "first", j.Name())
+	}
+
+	if _, ok := s.Job("missing"); ok {
+		t.Errorf("Expected no job registered as missing")
+	}
+
+	s.StopAll()
+	s.Wait()
+
+	if got := s.Jobs(); len(got) != 0 {
+		t.Errorf("Expected finished jobs to be removed, got %v", got)
+	}
+}
+
+func TestRegistryRejectsDuplicateName(t *testing.T) {
+	fc := clock.NewMockClock()
+	s := New()
+
+	job := Every(fc.NewTicker(time.Second))
+	s.Add("match", job)
+
+	err := s.Add("match", Every(fc.NewTicker(time.Second)))
+	if !errors.Is(err, ErrJobExists) {
+		t.Errorf("Expected %v got %v", ErrJobExists, err)
+	}
+
+	if !s.Stop("match") {
+		t.Errorf("Expected running job to be stopped")
+	}
+	s.Wait()
+
+	if err := s.Add("match", Every(fc.NewTicker(time.Second))); err != nil {
+		t.Errorf("Expected name to be reusable after job finished, got %v", err)
+	}
+	if s.Stop("missing") {
+		t.Errorf("Expected stopping an unknown job to report false")
+	}
+	s.StopAll()
+	s.Wait()
+}
+
+func TestRegistryWaitsForAllJobs(t *testing.T) {
+	fc := clock.NewMockClock()
+	s := New()
+	count := 0
+
+	job := Every(fc.NewTicker(time.Second)).Repeat(2)
+	s.Add("counter", job)
+	job.Do(func() { count++ })
+
+	fc.AddTime(time.Second)
+	s.Wait()
+
+	if count != 2 {
+		t.Errorf("Expected %d runs, got %d", 2, count)
+	}
+}
+
+func TestRegistryRunsJobsOnItsClock(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	s := New(WithClock(fc))
+	runs := make(chan time.Time)
+
+	job := MustCron("*/5 * * * *").Repeat(1)
+	s.Add("match", job)
+	job.DoFunc(func() {
+		runs <- fc.Now()
+	})
+
+	fc.AddTime(5 * time.Minute)
+	if got, want := <-runs, start.Add(5*time.Minute); !got.Equal(want) {
+		t.Errorf("Expected run at %v got %v", want, got)
+	}
+	s.Wait()
+}
+
+func TestRegistryRunsDelayedJobsOnItsClock(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	s := New(WithClock(fc))
+	runs := make(chan time.Time)
+
+	// the delay counts on the scheduler's clock rather than the real one
+	job := After(2 * time.Second)
+	s.Add("kick-off", job)
+	job.DoFunc(func() {
+		runs <- fc.Now()
+	})
+
+	fc.AddTime(2 * time.Second)
+	if got, want := <-runs, start.Add(2*time.Second); !got.Equal(want) {
+		t.Errorf("Expected run at %v got %v", want, got)
+	}
+	s.Wait()
+}
+
+func TestRegistryAddsStartedJob(t *testing.T) {
+	job := MustCron("* * * * *").DoFunc(func() {})
+
+	// a running job can't move over to the scheduler's clock
+	mocked := New(WithClock(clock.NewMockClock()))
+	if err := mocked.Add("match", job); !errors.Is(err, ErrJobStarted) {
+		t.Errorf("Expected %v got %v", ErrJobStarted, err)
+	}
+
+	s := New()
+	if err := s.Add("match", job); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := s.Job("match"); !ok {
+		t.Errorf("Expected started job to be registered")
+	}
+	s.StopAll()
+	s.Wait()
+}
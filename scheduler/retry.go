@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// RetryPolicy retries a failed run within the same tick, waiting an
+// exponentially growing backoff between attempts. The job carries on at its
+// normal cadence once a run succeeds or the attempts are used up.
+type RetryPolicy struct {
+	// Attempts per run including the first one, values below 2 disable retries
+	MaxAttempts int
+	// Backoff before the first retry
+	InitialBackoff time.Duration
+	// Upper bound of the backoff, unbounded when zero
+	MaxBackoff time.Duration
+	// Factor the backoff grows by after every retry, defaults to 2
+	Multiplier float64
+	// Fraction between 0 and 1 by which each backoff is randomly shortened
+	Jitter float64
+	// Decides if an error is worth retrying, all errors are retried when nil
+	Retryable func(error) bool
+}
+
+// Retry sets the policy for retrying failed runs
+func (j *Job) Retry(policy RetryPolicy) *Job {
+	j.retry = policy
+	return j
+}
+
+// shouldRetry reports if another attempt is allowed after the given failed attempt
+func (p RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// backoff calculates the wait before the given retry, starting at 1
+func (p RetryPolicy) backoff(retry int, random func() float64) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * math.Min(p.Jitter, 1) * random()
+	}
+	return time.Duration(d)
+}
+
+// execute performs a single run, retrying it according to the retry policy.
+// Retries wait for the job's limiter like the first attempt does.
+// Only the final failure is reported and returned.
+func (j *Job) execute(ctx context.Context, run int) error {
+	for attempt := 1; ; attempt++ {
+		stack, err := j.attempt(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// the lease was lost, see UseLocker
+		if ctx.Err() != nil || !j.retry.shouldRetry(attempt, err) || !j.sleep(j.retry.backoff(attempt, j.random)) || !j.throttle() {
+			j.reportError(&JobError{Job: j.Name(), Attempt: run, Retries: attempt - 1, Err: err, Stack: stack})
+			return err
+		}
+	}
+}
+
+// sleep waits for the given duration, returns false if the job was stopped meanwhile
+func (j *Job) sleep(d time.Duration) bool {
+	timer := j.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.Chan():
+		return true
+	case <-j.Ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy RetryPolicy
+		random float64
+		want   []time.Duration
+	}{
+		{
+			name:   "exponential backoff with default multiplier",
+			policy: RetryPolicy{InitialBackoff: time.Second},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "backoff capped at maximum",
+			policy: RetryPolicy{InitialBackoff: time.Second, Multiplier: 3, MaxBackoff: 5 * time.Second},
+			want:   []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "jitter shortens backoff",
+			policy: RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5},
+			random: 0.5,
+			want:   []time.Duration{750 * time.Millisecond, 1500 * time.Millisecond, 3 * time.Second, 6 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, want := range tc.want {
+				got := tc.policy.backoff(i+1, func() float64 { return tc.random })
+				if got != want {
+					t.Errorf("Retry %d: expected %v got %v", i+1, want, got)
+				}
+			}
+		})
+	}
+}
+
+// advanceUntil moves the mock clock forward in steps until done is signalled
+func advanceUntil(fc *clock.MockClock, step time.Duration, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(5 * time.Millisecond):
+			fc.AddTime(step)
+		}
+	}
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	fc := clock.NewMockClock()
+	errTransient := errors.New("503 service unavailable")
+	attempts := 0
+	reported := false
+
+	job := Every(fc.NewTicker(time.Hour)).Repeat(1).Retry(RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+	}).OnError(func(err *JobError) {
+		reported = true
+	})
+	job.clock = fc
+
+	job.DoCtx(func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	advanceUntil(fc, time.Second, job.done)
+
+	if attempts != 3 {
+		t.Errorf("Expected %d attempts, got %d", 3, attempts)
+	}
+	if reported {
+		t.Errorf("Expected no error reported once a retry succeeded")
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	errTransient := errors.New("503 service unavailable")
+	errPermanent := errors.New("410 gone")
+
+	testCases := []struct {
+		name        string
+		err         error
+		wantRetries int
+	}{
+		{name: "retryable error exhausts attempts", err: errTransient, wantRetries: 2},
+		{name: "non retryable error is not retried", err: errPermanent, wantRetries: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := clock.NewMockClock()
+			var reported *JobError
+
+			job := Every(fc.NewTicker(time.Hour)).Repeat(1).Retry(RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Second,
+				Retryable: func(err error) bool {
+					return errors.Is(err, errTransient)
+				},
+			}).OnError(func(err *JobError) {
+				reported = err
+			})
+			job.clock = fc
+
+			job.DoCtx(func(ctx context.Context) error {
+				return tc.err
+			})
+			advanceUntil(fc, time.Second, job.done)
+
+			if reported == nil {
+				t.Fatal("Expected final failure to be reported")
+			}
+			if !errors.Is(reported, tc.err) || reported.Retries != tc.wantRetries {
+				t.Errorf("Expected %v after %d retries, got %v after %d", tc.err, tc.wantRetries, reported.Err, reported.Retries)
+			}
+		})
+	}
+}
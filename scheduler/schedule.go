@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+// alignedSchedule fires on multiples of its period since the zero time, so
+// jobs aligned to the same period tick together across processes
+type alignedSchedule struct {
+	period time.Duration
+}
+
+func (s alignedSchedule) next(t time.Time) time.Time {
+	return t.Truncate(s.period).Add(s.period)
+}
+
+// onceSchedule fires a single time
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// delaySchedule fires once after a delay, counted from when the ticker
+// following it is created on the job's clock
+type delaySchedule struct {
+	delay time.Duration
+}
+
+func (s delaySchedule) next(t time.Time) time.Time {
+	return s.from(t).next(t)
+}
+
+// from resolves the delay against the time the ticker starts at
+func (s delaySchedule) from(start time.Time) schedule {
+	return onceSchedule{at: start.Add(s.delay)}
+}
+
+// After creates a job running once after the given delay
+func After(d time.Duration) *Job {
+	j := newJob(newScheduleTicker(realClock{}, delaySchedule{delay: d}))
+	j.immediate = false
+	j.repeats = 1
+	return j
+}
+
+// StartAt holds off the first run until the given time. Jobs that run
+// straight away on Do run at that time instead, ticks before it are dropped.
+func (j *Job) StartAt(t time.Time) *Job {
+	j.startAt = t
+	return j
+}
+
+// AlignTo makes the job tick on wall-clock multiples of d, e.g. on every full
+// minute, instead of the ticker it was created with. The first run happens on
+// the first boundary.
+func (j *Job) AlignTo(d time.Duration) *Job {
+	j.interval.Stop()
+	j.interval = newScheduleTicker(j.clock, alignedSchedule{period: d})
+	j.immediate = false
+	j.every = d
+	return j
+}
+
+// Reschedule replaces the job's ticker while it is running, the next run
+// happens on the new ticker's first tick. A run in progress is not affected.
+func (j *Job) Reschedule(ticker clock.Ticker) {
+	j.mu.Lock()
+	if j.finished {
+		j.mu.Unlock()
+		ticker.Stop()
+		return
+	}
+	old := j.interval
+	j.interval = ticker
+	j.mu.Unlock()
+
+	old.Stop()
+	select {
+	case j.rescheduled <- struct{}{}:
+	default:
+	}
+}
+
+// SetInterval makes the job run every d from now on, starting d from now.
+// Setting the interval the job already runs at keeps its cadence.
+func (j *Job) SetInterval(d time.Duration) {
+	now := j.clock.Now()
+
+	j.mu.Lock()
+	if j.every == d {
+		j.mu.Unlock()
+		return
+	}
+	j.every = d
+	j.spec = ""
+	// predict the next run from the new interval, see Stats
+	j.lastTick = now
+	j.period = d
+	j.mu.Unlock()
+
+	j.Reschedule(j.clock.NewTicker(d))
+}
+
+// adapt applies the delay suggested by the latest run
+func (j *Job) adapt() {
+	j.mu.Lock()
+	next := j.suggested
+	j.suggested = 0
+	j.mu.Unlock()
+
+	switch {
+	case next < 0:
+		j.Stop()
+	case next > 0:
+		j.SetInterval(next)
+	}
+}
+
+// ticker returns the ticker the job currently runs on
+func (j *Job) ticker() clock.Ticker {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.interval
+}
+
+// waitUntil blocks until the given time and discards a tick that arrived
+// meanwhile, returns false if the job was stopped while waiting
+func (j *Job) waitUntil(t time.Time) bool {
+	if d := t.Sub(j.clock.Now()); d > 0 && !j.sleep(d) {
+		return false
+	}
+
+	select {
+	case <-j.ticker().Chan():
+	default:
+	}
+	return true
+}
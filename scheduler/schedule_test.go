@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestAlignedScheduleNext(t *testing.T) {
+	s := alignedSchedule{period: time.Minute}
+
+	testCases := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "mid minute aligns to next full minute",
+			from: time.Date(2024, time.March, 2, 10, 0, 30, 500, time.UTC),
+			want: time.Date(2024, time.March, 2, 10, 1, 0, 0, time.UTC),
+		},
+		{
+			name: "on boundary moves to the following one",
+			from: time.Date(2024, time.March, 2, 10, 1, 0, 0, time.UTC),
+			want: time.Date(2024, time.March, 2, 10, 2, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := s.next(tc.from); !got.Equal(tc.want) {
+				t.Errorf("Expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJobAlignedToMinute(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 30, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	runs := make(chan time.Time)
+
+	job := Every(fc.NewTicker(time.Minute))
+	job.clock = fc
+	job.AlignTo(time.Minute).Repeat(2).DoFunc(func() {
+		runs <- fc.Now()
+	})
+
+	fc.AddTime(30 * time.Second)
+	if got, want := <-runs, start.Add(30*time.Second); !got.Equal(want) {
+		t.Errorf("Expected first run at %v got %v", want, got)
+	}
+
+	fc.AddTime(time.Minute)
+	if got, want := <-runs, start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("Expected second run at %v got %v", want, got)
+	}
+	job.Wait()
+}
+
+func TestJobStartAt(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	kickOff := start.Add(5 * time.Minute)
+	fc := clock.NewMockClock(start)
+	runs := make(chan time.Time, 1)
+
+	job := Every(fc.NewTicker(time.Minute))
+	job.clock = fc
+	job.StartAt(kickOff).Repeat(1).DoFunc(func() {
+		runs <- fc.Now()
+	})
+
+	advanceUntil(fc, time.Minute, job.done)
+
+	if got := <-runs; got.Before(kickOff) {
+		t.Errorf("Expected first run not before %v got %v", kickOff, got)
+	}
+}
+
+func TestAfterRunsOnce(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	runs := 0
+
+	started := time.Now()
+	After(delay).DoFunc(func() {
+		runs++
+	}).Wait()
+
+	if elapsed := time.Since(started); elapsed < delay {
+		t.Errorf("Expected run after %v, finished after %v", delay, elapsed)
+	}
+	if runs != 1 {
+		t.Errorf("Expected %d runs, got %d", 1, runs)
+	}
+}
+
+func TestJobSetInterval(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	runs := make(chan time.Time)
+
+	job := Every(fc.NewTicker(time.Minute))
+	job.clock = fc
+	job.Repeat(3).DoFunc(func() {
+		runs <- fc.Now()
+	})
+	<-runs
+
+	// kick off, poll more often
+	job.SetInterval(5 * time.Second)
+	if got, want := job.Stats().NextRun, start.Add(5*time.Second); !got.Equal(want) {
+		t.Errorf("Expected next run at %v got %v", want, got)
+	}
+
+	for i := 1; i <= 2; i++ {
+		advanceUntil(fc, time.Second, receivedRun(runs, func(got time.Time) {
+			if want := start.Add(time.Duration(i) * 5 * time.Second); !got.Equal(want) {
+				t.Errorf("Expected run at %v got %v", want, got)
+			}
+		}))
+	}
+	job.Wait()
+}
+
+func TestJobDoAdaptive(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	var runs []time.Time
+
+	// pre-match, live, full time
+	delays := []time.Duration{time.Minute, 5 * time.Second, -1}
+	job := Every(fc.NewTicker(time.Hour))
+	job.clock = fc
+	job.DoAdaptive(func(context.Context) (time.Duration, error) {
+		runs = append(runs, fc.Now())
+		return delays[len(runs)-1], nil
+	})
+
+	advanceUntil(fc, time.Second, job.done)
+
+	want := []time.Time{start, start.Add(time.Minute), start.Add(time.Minute + 5*time.Second)}
+	if len(runs) != len(want) {
+		t.Fatalf("Expected %d runs got %d", len(want), len(runs))
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("Expected run %d at %v got %v", i+1, want[i], runs[i])
+		}
+	}
+}
+
+// receivedRun closes the returned channel once a run was received and checked
+func receivedRun(runs <-chan time.Time, check func(time.Time)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		check(<-runs)
+		close(done)
+	}()
+	return done
+}
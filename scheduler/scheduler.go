@@ -3,26 +3,105 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/mixer/clock"
 )
 
+// Scheduler keeps track of named jobs and manages them as a group
 type Scheduler interface {
-	Every(interval clock.Ticker)
-	Repeat(repeats int)
-	Do(fn any, args ...any) *Job
+	Add(name string, job *Job) error
+	Job(name string) (*Job, bool)
+	Jobs() []string
+	Stop(name string) bool
+	StopAll()
 	Wait()
 }
 
+// State of a job over its lifetime
+type State int
+
+const (
+	Idle State = iota
+	Running
+	Paused
+	Finished
+)
+
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "idle"
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	case Finished:
+		return "finished"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Overlap policy applied when a tick arrives while a run is still in progress
+type Overlap int
+
+const (
+	OverlapQueue Overlap = iota
+	OverlapSkip
+	OverlapConcurrent
+)
+
 type Job struct {
 	Ctx context.Context
 	Cancel context.CancelFunc
-	interval clock.Ticker
-	jobFunc func()
+	done chan struct{}
+	// wakes the run loop up once the ticker was replaced
+	rescheduled chan struct{}
+
+	// set before Do
+	jobFunc func(ctx context.Context) error
+	errorHandler func(*JobError)
+	retry RetryPolicy
+	startAt time.Time
+	windows []Window
+	backlog int
+	hooks hooks
+	clock clock.Clock
+	timeout time.Duration
 	repeats int
-	running bool
-	// quit chan struct{}
+	overlap Overlap
+	maxConcurrent int
+	priority int
+	jitter jitter
+	random func() float64
+	// run straight away on Do rather than waiting for the first tick
+	immediate bool
+
+	mu sync.Mutex
+	// guarded by mu
+	name string
+	interval clock.Ticker
+	// cron expression the job was created from
+	spec string
+	// period of aligned jobs and jobs given one with Interval or SetInterval
+	every time.Duration
+	stats Stats
+	lastTick time.Time
+	period time.Duration
+	// delay until the next run suggested by the function, see DoAdaptive
+	suggested time.Duration
+	store JobStore
+	lease lease
+	limiter *Limiter
+	pool *pool
+	started bool
+	paused bool
+	finished bool
+	// removed from its scheduler with Stop, see forget
+	removed bool
 }
 
 func newJob(interval clock.Ticker) *Job {
@@ -33,6 +112,11 @@ func newJob(interval clock.Ticker) *Job {
 		interval: interval,
 		jobFunc: nil,
 		repeats: -1,
+		immediate: true,
+		done: make(chan struct{}),
+		rescheduled: make(chan struct{}, 1),
+		clock: realClock{},
+		random: rand.Float64,
 	}
 	return j
 }
@@ -47,6 +131,63 @@ func (j *Job) Repeat(repeats int) *Job {
 	return j
 }
 
+// Interval declares the period of the ticker given to Every, which tickers
+// don't reveal, so Jitter applies from the first run
+func (j *Job) Interval(d time.Duration) *Job {
+	j.every = d
+	return j
+}
+
+// Clock sets the clock the job measures time with, e.g. a mock clock in tests.
+// The job's ticker should come from the same clock. Set it before Do.
+func (j *Job) Clock(c clock.Clock) *Job {
+	j.mu.Lock()
+	j.clock = c
+	// tickers following a schedule, see Cron and AlignTo, move over to the new clock
+	t, scheduled := j.interval.(*scheduleTicker)
+	if scheduled {
+		j.interval = newScheduleTicker(c, t.schedule)
+	}
+	j.mu.Unlock()
+
+	if scheduled {
+		t.Stop()
+		select {
+		case j.rescheduled <- struct{}{}:
+		default:
+		}
+	}
+	return j
+}
+
+// Timeout limits how long a single run may take. The deadline is only
+// enforced through the context passed to functions accepting one.
+func (j *Job) Timeout(d time.Duration) *Job {
+	j.timeout = d
+	return j
+}
+
+// SkipOverlapping drops ticks that arrive while the previous run is still in progress
+func (j *Job) SkipOverlapping() *Job {
+	j.overlap = OverlapSkip
+	return j
+}
+
+// QueueOverlapping keeps at most one pending run for ticks that arrive while
+// the previous run is still in progress. This is the default policy.
+func (j *Job) QueueOverlapping() *Job {
+	j.overlap = OverlapQueue
+	return j
+}
+
+// Concurrent lets runs overlap, at most max at a time. Ticks arriving
+// while max runs are in progress are dropped, max <= 0 means no limit.
+func (j *Job) Concurrent(max int) *Job {
+	j.overlap = OverlapConcurrent
+	j.maxConcurrent = max
+	return j
+}
+
 func allArgumentsOfSameType(args ...any) bool {
 	m := map[reflect.Type]bool{}
 	for _ , x := range args {
@@ -84,12 +225,43 @@ func validArguments(fArgs reflect.Type, args ...any) bool {
 	return false
 }
 
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// takesContext reports if the function's first parameter is a context.Context
+func takesContext(fn reflect.Type) bool {
+	return fn.NumIn() > 0 && fn.In(0) == contextType
+}
+
+// withoutContext returns the function type with its leading context parameter removed
+func withoutContext(fn reflect.Type) reflect.Type {
+	in := make([]reflect.Type, 0, fn.NumIn()-1)
+	for i := 1; i < fn.NumIn(); i++ {
+		in = append(in, fn.In(i))
+	}
+	out := make([]reflect.Type, 0, fn.NumOut())
+	for i := 0; i < fn.NumOut(); i++ {
+		out = append(out, fn.Out(i))
+	}
+	return reflect.FuncOf(in, out, fn.IsVariadic() && len(in) > 0)
+}
+
 // Runs function in a goroutine and returns the job.
-// Optional args parameter to provide paramters to provided function
+// Optional args parameter to provide paramters to provided function.
+// If the function's first parameter is a context.Context it receives a
+// context for each run, cancelled when the job stops or the run times out.
 func (j *Job) Do(fn any, args ...any) *Job {
 	f := reflect.ValueOf(fn)
 
-	if !validArguments(f.Type(), args...) {
+	fnType := f.Type()
+	withContext := takesContext(fnType)
+	if withContext {
+		fnType = withoutContext(fnType)
+	}
+
+	if !validArguments(fnType, args...) {
 		fmt.Println("Invalid number of arguments")
 		return nil
 	}
@@ -99,30 +271,211 @@ func (j *Job) Do(fn any, args ...any) *Job {
 		in[i] = reflect.ValueOf(param)
 	}
 
-	go func() {
-		defer j.interval.Stop()
-		defer func(){j.running = false}()
-		i := 0
-		j.running = true
+	// a trailing error result is reported as a failed run
+	returnsError := fnType.NumOut() > 0 && fnType.Out(fnType.NumOut()-1) == errorType
 
-		L: for{
-			i++
-			f.Call(in)
+	j.jobFunc = func(ctx context.Context) error {
+		params := in
+		if withContext {
+			params = append([]reflect.Value{reflect.ValueOf(ctx)}, in...)
+		}
+
+		out := f.Call(params)
+		if !returnsError {
+			return nil
+		}
+		err, _ := out[len(out)-1].Interface().(error)
+		return err
+	}
+	j.start()
+	return j
+}
+
+// DoFunc is the type-safe counterpart of Do for functions without parameters
+func (j *Job) DoFunc(fn func()) *Job {
+	j.jobFunc = func(context.Context) error {
+		fn()
+		return nil
+	}
+	j.start()
+	return j
+}
+
+// DoCtx runs a function receiving the context of each run,
+// a returned error is reported as a failed run
+func (j *Job) DoCtx(fn func(context.Context) error) *Job {
+	j.jobFunc = fn
+	j.start()
+	return j
+}
 
-			if(!j.Indefinite() && i >= j.repeats){
-				j.Cancel()
+// DoAdaptive runs a function that decides the delay until the job's next run.
+// A positive delay is applied with SetInterval once the run is over, zero
+// keeps the current cadence and a negative delay stops the job.
+func (j *Job) DoAdaptive(fn func(context.Context) (time.Duration, error)) *Job {
+	j.jobFunc = func(ctx context.Context) error {
+		next, err := fn(ctx)
+		// applied once the run is over, so the next tick isn't taken for an overlap
+		j.mu.Lock()
+		j.suggested = next
+		j.mu.Unlock()
+		return err
+	}
+	j.start()
+	return j
+}
+
+// Do1 is the type-safe counterpart of Do for functions taking a single argument.
+// Go does not allow type parameters on methods, hence the job parameter.
+func Do1[A any](j *Job, fn func(A), a A) *Job {
+	return j.DoFunc(func() {
+		fn(a)
+	})
+}
+
+func (j *Job) start() {
+	j.mu.Lock()
+	j.started = true
+	// ticks of a declared interval are predicted from the start, see Stats
+	if j.every > 0 && j.lastTick.IsZero() {
+		j.lastTick = j.clock.Now()
+		j.period = j.every
+	}
+	j.mu.Unlock()
+
+	go j.run()
+}
+
+func (j *Job) run() {
+	defer j.finish()
+
+	// carries whether the run went ahead, see UseLocker
+	runDone := make(chan bool)
+	runs, inFlight := 0, 0
+	pending := false
+
+	exhausted := func() bool {
+		return !j.Indefinite() && runs >= j.repeats
+	}
+	launch := func() {
+		// ticks received while paused are dropped, the repeat counter is kept
+		if exhausted() || j.State() == Paused {
+			return
+		}
+		runs++
+		inFlight++
+		attempt := runs
+		j.submitJittered(func() {
+			runDone <- j.perform(attempt)
+		})
+	}
+	trigger := func() {
+		if !j.inWindow(j.clock.Now()) {
+			return
+		}
+		if inFlight > 0 {
+			switch j.overlap {
+			case OverlapSkip:
+				return
+			case OverlapQueue:
+				pending = true
 				return
+			case OverlapConcurrent:
+				if j.maxConcurrent > 0 && inFlight >= j.maxConcurrent {
+					return
+				}
 			}
+		}
+		launch()
+	}
 
-			select {
-				case <- j.interval.Chan():
-					continue
-				case <- j.Ctx.Done():
-					break L
-			}
+	// runs missed while the process was down, see UseStore
+	for ; j.backlog > 0 && !exhausted() && j.Ctx.Err() == nil; j.backlog-- {
+		attempt := runs + 1
+		j.submit(func() {
+			runDone <- j.perform(attempt)
+		})
+		if <-runDone {
+			runs++
 		}
-	}()
-	return j
+		j.adapt()
+	}
+
+	if !j.startAt.IsZero() && !j.waitUntil(j.startAt) {
+		return
+	}
+
+	if j.immediate {
+		trigger()
+	}
+
+	for {
+		if exhausted() && inFlight == 0 {
+			j.Cancel()
+			return
+		}
+
+		// stop consuming ticks once the last run has been started
+		var ticks <-chan time.Time
+		if !exhausted() {
+			ticks = j.ticker().Chan()
+		}
+
+		select {
+			case tick := <- ticks:
+				j.recordTick(tick)
+				trigger()
+			case <- j.rescheduled:
+				// pick up the new ticker
+			case ran := <- runDone:
+				inFlight--
+				if !ran {
+					runs--
+				}
+				j.adapt()
+				if pending && !exhausted() {
+					pending = false
+					launch()
+				}
+			case <- j.Ctx.Done():
+				for ; inFlight > 0; inFlight-- {
+					<-runDone
+				}
+				return
+		}
+	}
+}
+
+// attempt calls the job function once, turning a panic into an error
+func (j *Job) attempt(parent context.Context) (stack []byte, err error) {
+	ctx, cancel := j.runContext(parent)
+	defer cancel()
+	defer recoverRun(&err, &stack)
+
+	return nil, j.jobFunc(ctx)
+}
+
+// runContext derives the context of a single attempt from the run's context
+func (j *Job) runContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if j.timeout > 0 {
+		return context.WithTimeout(parent, j.timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+func (j *Job) finish() {
+	j.unlock()
+	j.forget()
+
+	j.mu.Lock()
+	j.interval.Stop()
+	j.finished = true
+	j.mu.Unlock()
+
+	if j.hooks.complete != nil {
+		j.hooks.complete()
+	}
+	close(j.done)
 }
 
 func (j *Job) Stop() {
@@ -132,6 +485,52 @@ func (j *Job) Stop() {
 // Blocking until job finishes
 func (j *Job) Wait() {
 	<-j.Ctx.Done()
+
+	j.mu.Lock()
+	started := j.started
+	j.mu.Unlock()
+
+	// let an in-flight run complete
+	if started {
+		<-j.done
+	}
+}
+
+// Pause suspends the job, ticks are skipped until it is resumed
+func (j *Job) Pause() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.paused = true
+}
+
+// Resume continues running a paused job from the next tick
+func (j *Job) Resume() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.paused = false
+}
+
+func (j *Job) State() State {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case j.finished:
+		return Finished
+	case j.paused:
+		return Paused
+	case !j.started:
+		return Idle
+	default:
+		return Running
+	}
+}
+
+// Name the job was registered under in a Scheduler
+func (j *Job) Name() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.name
 }
 
 // Checks if job running indefinitely 
@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -133,7 +135,7 @@ func TestSchedulerCancelJob(t *testing.T) {
 	fnParams := []string{"Hello"}
 	instanceVar := ""
 
-	ticker := clock.C.NewTicker(interval)
+	ticker := realClock{}.NewTicker(interval)
 
 	arguments := []interface{}{&instanceVar}
 	for _, x := range fnParams {
@@ -245,6 +247,318 @@ func TestSchedulerFunctionArgumentsValidation(t *testing.T) {
 	}
 }
 
+// manualTicker delivers a tick only when asked to, a send completes once
+// the job has received the tick
+type manualTicker struct {
+	c chan time.Time
+}
+
+func newManualTicker() *manualTicker {
+	return &manualTicker{c: make(chan time.Time)}
+}
+
+func (m *manualTicker) Chan() <-chan time.Time {
+	return m.c
+}
+
+func (m *manualTicker) Stop() {}
+
+func (m *manualTicker) tick() {
+	m.c <- time.Now()
+}
+
+func TestSchedulerPauseAndResume(t *testing.T) {
+	ticker := newManualTicker()
+	runs := make(chan struct{})
+
+	job := Every(ticker).Repeat(2)
+	if job.State() != Idle {
+		t.Errorf("Expected state %v got %v", Idle, job.State())
+	}
+
+	job.Do(func() {
+		runs <- struct{}{}
+	})
+	<-runs
+
+	job.Pause()
+	if job.State() != Paused {
+		t.Errorf("Expected state %v got %v", Paused, job.State())
+	}
+
+	ticker.tick()
+	ticker.tick()
+	select {
+	case <-runs:
+		t.Fatal("Paused job should not run")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	job.Resume()
+	if job.State() != Running {
+		t.Errorf("Expected state %v got %v", Running, job.State())
+	}
+
+	// repeat counter survived the pause, so this is the final run
+	ticker.tick()
+	<-runs
+	job.Wait()
+
+	if job.State() != Finished {
+		t.Errorf("Expected state %v got %v", Finished, job.State())
+	}
+}
+
+// blockingRun returns a job function that reports each run on started and
+// blocks until released
+func blockingRun(started, release chan struct{}) func() {
+	return func() {
+		started <- struct{}{}
+		<-release
+	}
+}
+
+func expectNoRun(t *testing.T, started chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+		t.Fatal("Unexpected run started")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSchedulerSkipOverlappingRuns(t *testing.T) {
+	ticker := newManualTicker()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	job := Every(ticker).Repeat(2).SkipOverlapping().Do(blockingRun(started, release))
+	<-started
+
+	// both ticks arrive while the first run is in progress
+	ticker.tick()
+	ticker.tick()
+	release <- struct{}{}
+	expectNoRun(t, started)
+
+	ticker.tick()
+	<-started
+	release <- struct{}{}
+	job.Wait()
+}
+
+func TestSchedulerQueueOverlappingRuns(t *testing.T) {
+	ticker := newManualTicker()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	job := Every(ticker).Repeat(3).QueueOverlapping().Do(blockingRun(started, release))
+	<-started
+
+	// only a single run is queued however many ticks arrive
+	ticker.tick()
+	ticker.tick()
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	expectNoRun(t, started)
+
+	ticker.tick()
+	<-started
+	release <- struct{}{}
+	job.Wait()
+}
+
+func TestSchedulerConcurrentRuns(t *testing.T) {
+	ticker := newManualTicker()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	job := Every(ticker).Repeat(3).Concurrent(2).Do(blockingRun(started, release))
+	<-started
+
+	ticker.tick()
+	<-started
+
+	// limit of two concurrent runs reached
+	ticker.tick()
+	expectNoRun(t, started)
+
+	release <- struct{}{}
+	release <- struct{}{}
+	expectNoRun(t, started)
+
+	ticker.tick()
+	<-started
+	release <- struct{}{}
+	job.Wait()
+}
+
+func TestSchedulerPassesContextToFunction(t *testing.T) {
+	fc := clock.NewMockClock()
+	result := ""
+
+	fn := func(ctx context.Context, result *string, args ...string) {
+		if ctx == nil {
+			return
+		}
+		for _, x := range args {
+			*result = (*result) + x
+		}
+	}
+
+	job := Every(fc.NewTicker(time.Second)).Repeat(1).Do(fn, &result, "Hello", "World")
+	if job == nil {
+		t.Fatal("Expected arguments after context to be valid")
+	}
+	job.Wait()
+
+	if result != "HelloWorld" {
+		t.Errorf("Expected %s got %s", "HelloWorld", result)
+	}
+}
+
+func TestSchedulerRunTimeout(t *testing.T) {
+	fc := clock.NewMockClock()
+	var err error
+
+	fn := func(ctx context.Context) {
+		<-ctx.Done()
+		err = ctx.Err()
+	}
+
+	job := Every(fc.NewTicker(time.Second)).Repeat(1).Timeout(10 * time.Millisecond).Do(fn)
+	job.Wait()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSchedulerStopCancelsRunningFunction(t *testing.T) {
+	fc := clock.NewMockClock()
+	started := make(chan struct{})
+	var err error
+
+	fn := func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		err = ctx.Err()
+	}
+
+	job := Every(fc.NewTicker(time.Second)).Do(fn)
+	<-started
+	job.Stop()
+	job.Wait()
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestSchedulerRecoversPanic(t *testing.T) {
+	ticker := newManualTicker()
+	errs := make(chan *JobError, 2)
+	runs := 0
+
+	fn := func() {
+		runs++
+		if runs == 1 {
+			panic("boom")
+		}
+	}
+
+	job := Every(ticker).Repeat(2).OnError(func(err *JobError) {
+		errs <- err
+	}).Do(fn)
+
+	err := <-errs
+	if !errors.Is(err, ErrPanic) {
+		t.Errorf("Expected %v got %v", ErrPanic, err.Err)
+	}
+	if err.Attempt != 1 {
+		t.Errorf("Expected attempt %d got %d", 1, err.Attempt)
+	}
+	if len(err.Stack) == 0 {
+		t.Errorf("Expected stack trace of the panic")
+	}
+
+	// job keeps running after a panic
+	ticker.tick()
+	job.Wait()
+
+	if runs != 2 {
+		t.Errorf("Expected %d runs, got %d", 2, runs)
+	}
+	if len(errs) != 0 {
+		t.Errorf("Expected a single error, got %v", <-errs)
+	}
+}
+
+func TestSchedulerReportsReturnedError(t *testing.T) {
+	ticker := newManualTicker()
+	errBoom := errors.New("boom")
+	errs := make(chan *JobError, 2)
+
+	fn := func(ctx context.Context, attempt *int) error {
+		*attempt++
+		if *attempt == 2 {
+			return errBoom
+		}
+		return nil
+	}
+
+	s := New()
+	job := Every(ticker).Repeat(2).OnError(func(err *JobError) {
+		errs <- err
+	})
+	s.Add("fixtures", job)
+
+	attempt := 0
+	job.Do(fn, &attempt)
+	ticker.tick()
+	job.Wait()
+
+	err := <-errs
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Expected %v got %v", errBoom, err.Err)
+	}
+	if err.Job != "fixtures" || err.Attempt != 2 {
+		t.Errorf("Expected error for run %d of %s, got %v", 2, "fixtures", err)
+	}
+	if err.Stack != nil {
+		t.Errorf("Expected no stack trace for returned error")
+	}
+}
+
+func TestSchedulerTypedDo(t *testing.T) {
+	fc := clock.NewMockClock()
+
+	count := 0
+	Every(fc.NewTicker(time.Second)).Repeat(1).DoFunc(func() {
+		count++
+	}).Wait()
+	if count != 1 {
+		t.Errorf("Expected %d runs, got %d", 1, count)
+	}
+
+	result := ""
+	Do1(Every(fc.NewTicker(time.Second)).Repeat(1), func(s string) {
+		result = s
+	}, "Hello").Wait()
+	if result != "Hello" {
+		t.Errorf("Expected %s got %s", "Hello", result)
+	}
+
+	errBoom := errors.New("boom")
+	var reported error
+	Every(fc.NewTicker(time.Second)).Repeat(1).OnError(func(err *JobError) {
+		reported = err.Err
+	}).DoCtx(func(ctx context.Context) error {
+		return errBoom
+	}).Wait()
+	if reported != errBoom {
+		t.Errorf("Expected %v got %v", errBoom, reported)
+	}
+}
+
 // func TestSchedulerThrowsError(t *testing.T) {
 // 	const (
 // 		interval time.Duration = 1 * time.Second
@@ -266,4 +580,4 @@ func TestSchedulerFunctionArgumentsValidation(t *testing.T) {
 // 	if runCount != expectedRunCount {
 // 		t.Errorf("Expected %d runs, got %d", expectedRunCount, runCount)
 // 	}
-// }
\ No newline at end of file
+// }
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// Stats is a snapshot of a job's runs
+type Stats struct {
+	Runs      int
+	Successes int
+	Failures  int
+	// Start and end of the most recent run, the end is zero while it is in progress
+	LastStart    time.Time
+	LastEnd      time.Time
+	LastDuration time.Duration
+	// Next expected tick, zero if unknown or the job is finished
+	NextRun time.Time
+	// Error of the most recent failed run
+	LastError error
+}
+
+// nextTicker is implemented by tickers that know when they fire next
+type nextTicker interface {
+	nextTick() time.Time
+}
+
+// Stats returns a snapshot of the job's run statistics
+func (j *Job) Stats() Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := j.stats
+	if !j.finished {
+		stats.NextRun = j.nextRun()
+	}
+	return stats
+}
+
+// nextRun predicts the next tick, for plain tickers from the gap between the last two ticks
+func (j *Job) nextRun() time.Time {
+	if t, ok := j.interval.(nextTicker); ok {
+		return t.nextTick()
+	}
+	if j.period > 0 {
+		return j.lastTick.Add(j.period)
+	}
+	return time.Time{}
+}
+
+func (j *Job) recordTick(tick time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.lastTick.IsZero() {
+		j.period = tick.Sub(j.lastTick)
+	}
+	j.lastTick = tick
+}
+
+func (j *Job) recordStart(start time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.Runs++
+	j.stats.LastStart = start
+	j.stats.LastEnd = time.Time{}
+}
+
+func (j *Job) recordEnd(start time.Time, duration time.Duration, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.LastEnd = start.Add(duration)
+	j.stats.LastDuration = duration
+	if err != nil {
+		j.stats.Failures++
+		j.stats.LastError = err
+		return
+	}
+	j.stats.Successes++
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestJobStats(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+	errBoom := errors.New("boom")
+	runs := make(chan struct{})
+
+	job := Every(fc.NewTicker(time.Minute)).Repeat(3).OnError(func(*JobError) {})
+	job.clock = fc
+
+	attempt := 0
+	job.DoCtx(func(ctx context.Context) error {
+		defer func() { runs <- struct{}{} }()
+		attempt++
+		if attempt == 2 {
+			return errBoom
+		}
+		return nil
+	})
+
+	<-runs
+	fc.AddTime(time.Minute)
+	<-runs
+	fc.AddTime(time.Minute)
+	<-runs
+	job.Wait()
+
+	stats := job.Stats()
+	if stats.Runs != 3 || stats.Successes != 2 || stats.Failures != 1 {
+		t.Errorf("Expected 3 runs, 2 successes and 1 failure got %+v", stats)
+	}
+	if stats.LastError != errBoom {
+		t.Errorf("Expected last error %v got %v", errBoom, stats.LastError)
+	}
+	if want := start.Add(2 * time.Minute); !stats.LastStart.Equal(want) {
+		t.Errorf("Expected last start %v got %v", want, stats.LastStart)
+	}
+	if stats.LastEnd.Before(stats.LastStart) {
+		t.Errorf("Expected last end after last start, got %v", stats.LastEnd)
+	}
+	if !stats.NextRun.IsZero() {
+		t.Errorf("Expected no next run for finished job, got %v", stats.NextRun)
+	}
+}
+
+func TestJobStatsNextRun(t *testing.T) {
+	start := time.Date(2024, time.March, 2, 10, 0, 30, 0, time.UTC)
+	fc := clock.NewMockClock(start)
+
+	job := Every(fc.NewTicker(time.Minute))
+	job.clock = fc
+	job.AlignTo(time.Minute).DoFunc(func() {})
+	defer job.Stop()
+
+	if got, want := job.Stats().NextRun, start.Add(30*time.Second); !got.Equal(want) {
+		t.Errorf("Expected next run %v got %v", want, got)
+	}
+}
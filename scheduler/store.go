@@ -0,0 +1,234 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JobRecord is the persisted state of a job
+type JobRecord struct {
+	Name string `json:"name"`
+	// Cron expression of jobs created with Cron
+	Schedule string `json:"schedule,omitempty"`
+	// Period between runs, zero if not known yet
+	Interval time.Duration `json:"interval,omitempty"`
+	// Runs left, -1 for jobs running indefinitely
+	Remaining int       `json:"remaining"`
+	LastRun   time.Time `json:"lastRun"`
+}
+
+// JobStore persists job state so it survives restarts
+type JobStore interface {
+	Load(name string) (JobRecord, bool, error)
+	Save(record JobRecord) error
+	Delete(name string) error
+}
+
+// CatchUp decides what happens to runs missed while the process was down
+type CatchUp int
+
+const (
+	// CatchUpSkip drops missed runs and carries on at the job's cadence
+	CatchUpSkip CatchUp = iota
+	// CatchUpOnce makes up for any number of missed runs with a single run
+	CatchUpOnce
+	// CatchUpAll runs every missed run straight away, within the remaining repeats
+	CatchUpAll
+)
+
+// FileStore keeps job records in a JSON file
+type FileStore struct {
+	path    string
+	mu      sync.Mutex
+	records map[string]JobRecord
+}
+
+// NewFileStore opens the store at path, the file is created on first save
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		records: make(map[string]JobRecord),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("job store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileStore) Load(name string) (JobRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[name]
+	return record, ok, nil
+}
+
+func (s *FileStore) Save(record JobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Name] = record
+	return s.write()
+}
+
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, name)
+	return s.write()
+}
+
+func (s *FileStore) write() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces the file atomically so a crash never leaves it half written
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// UseStore persists the state of jobs added from now on. A job added under
+// the name of a stored record resumes with its remaining repeats and cadence,
+// handling runs missed in the meantime according to the catch-up policy.
+// The record is deleted once the job has no runs left or is removed with Stop.
+func (s *scheduler) UseStore(store JobStore, catchUp CatchUp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+	s.catchUp = catchUp
+}
+
+// maxCatchUp bounds the number of missed runs made up for
+const maxCatchUp = 1000
+
+// persist saves the job's state after a run
+func (j *Job) persist() {
+	j.mu.Lock()
+	store := j.store
+	if store == nil {
+		j.mu.Unlock()
+		return
+	}
+
+	record := JobRecord{
+		Name:      j.name,
+		Schedule:  j.spec,
+		Interval:  j.every,
+		Remaining: -1,
+		LastRun:   j.stats.LastStart,
+	}
+	if record.Interval == 0 {
+		record.Interval = j.period
+	}
+	if !j.Indefinite() {
+		record.Remaining = max(j.repeats-j.stats.Runs, 0)
+	}
+	j.mu.Unlock()
+
+	if err := store.Save(record); err != nil {
+		slog.Warn("Saving job state failed.", "job", record.Name, "error", err)
+	}
+}
+
+// forget deletes the job's record once it has no runs left or was removed
+// from the scheduler. Jobs stopped otherwise, e.g. on shutdown, keep their
+// record to resume from.
+func (j *Job) forget() {
+	j.mu.Lock()
+	store, name := j.store, j.name
+	done := j.removed || (!j.Indefinite() && j.stats.Runs >= j.repeats)
+	j.mu.Unlock()
+
+	if store == nil || !done {
+		return
+	}
+	if err := store.Delete(name); err != nil {
+		slog.Warn("Deleting job state failed.", "job", name, "error", err)
+	}
+}
+
+// restore resumes the job from its stored record
+func (j *Job) restore(record JobRecord, catchUp CatchUp, now time.Time) {
+	if record.Remaining >= 0 {
+		j.repeats = record.Remaining
+	}
+	if record.LastRun.IsZero() {
+		return
+	}
+
+	missed, next := missedRuns(record, now)
+	switch catchUp {
+	case CatchUpAll:
+		j.backlog = missed
+	case CatchUpOnce:
+		j.backlog = min(missed, 1)
+	}
+
+	// carry on with the cadence from the last run rather than restarting it on Do
+	if j.immediate && next.After(j.startAt) {
+		j.StartAt(next)
+	}
+}
+
+// missedRuns counts the runs that were due between the last run and now,
+// and returns when the next one is due
+func missedRuns(record JobRecord, now time.Time) (int, time.Time) {
+	limit := maxCatchUp
+	if record.Remaining >= 0 {
+		limit = min(limit, record.Remaining)
+	}
+
+	if record.Schedule == "" {
+		if record.Interval <= 0 {
+			return 0, time.Time{}
+		}
+		missed := int(now.Sub(record.LastRun) / record.Interval)
+		next := record.LastRun.Add(time.Duration(missed+1) * record.Interval)
+		return max(min(missed, limit), 0), next
+	}
+
+	cron, err := parseCron(record.Schedule)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	missed := 0
+	for t := cron.next(record.LastRun); !t.IsZero() && !t.After(now) && missed < limit; t = cron.next(t) {
+		missed++
+	}
+	return missed, cron.next(now)
+}
@@ -0,0 +1,219 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestFileStoreSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	record := JobRecord{
+		Name:      "909663",
+		Interval:  2 * time.Second,
+		Remaining: 5,
+		LastRun:   time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Save(record); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, ok, _ := reopened.Load(record.Name)
+	if !ok || got != record {
+		t.Errorf("Expected %+v got %+v", record, got)
+	}
+
+	reopened.Delete(record.Name)
+	if _, ok, _ := reopened.Load(record.Name); ok {
+		t.Errorf("Expected record to be deleted")
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	lastRun := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		record     JobRecord
+		now        time.Time
+		wantMissed int
+		wantNext   time.Time
+	}{
+		{
+			name:       "nothing missed",
+			record:     JobRecord{Interval: time.Minute, Remaining: -1, LastRun: lastRun},
+			now:        lastRun.Add(30 * time.Second),
+			wantMissed: 0,
+			wantNext:   lastRun.Add(time.Minute),
+		},
+		{
+			name:       "interval runs missed",
+			record:     JobRecord{Interval: time.Minute, Remaining: -1, LastRun: lastRun},
+			now:        lastRun.Add(3*time.Minute + 30*time.Second),
+			wantMissed: 3,
+			wantNext:   lastRun.Add(4 * time.Minute),
+		},
+		{
+			name:       "missed runs limited by remaining repeats",
+			record:     JobRecord{Interval: time.Minute, Remaining: 2, LastRun: lastRun},
+			now:        lastRun.Add(3*time.Minute + 30*time.Second),
+			wantMissed: 2,
+			wantNext:   lastRun.Add(4 * time.Minute),
+		},
+		{
+			name:       "cron runs missed",
+			record:     JobRecord{Schedule: "*/5 * * * *", Remaining: -1, LastRun: lastRun},
+			now:        lastRun.Add(12 * time.Minute),
+			wantMissed: 2,
+			wantNext:   lastRun.Add(15 * time.Minute),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			missed, next := missedRuns(tc.record, tc.now)
+			if missed != tc.wantMissed || !next.Equal(tc.wantNext) {
+				t.Errorf("Expected %d missed and next at %v got %d and %v", tc.wantMissed, tc.wantNext, missed, next)
+			}
+		})
+	}
+}
+
+func TestSchedulerRestoresJobState(t *testing.T) {
+	now := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(now)
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "jobs.json"))
+	store.Save(JobRecord{Name: "match", Interval: time.Minute, Remaining: 10, LastRun: now.Add(-3*time.Minute - 30*time.Second)})
+
+	s := New()
+	s.UseStore(store, CatchUpAll)
+
+	runs := make(chan struct{})
+	job := Every(newManualTicker()).Repeat(20)
+	job.clock = fc
+	if err := s.Add("match", job); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if job.repeats != 10 {
+		t.Errorf("Expected %d remaining repeats got %d", 10, job.repeats)
+	}
+	if want := now.Add(30 * time.Second); !job.startAt.Equal(want) {
+		t.Errorf("Expected cadence to resume at %v got %v", want, job.startAt)
+	}
+
+	job.DoFunc(func() {
+		runs <- struct{}{}
+	})
+
+	// three missed runs are caught up straight away
+	for i := 0; i < 3; i++ {
+		<-runs
+	}
+	expectNoRun(t, runs)
+	job.Stop()
+	job.Wait()
+
+	record, _, _ := store.Load("match")
+	if record.Remaining != 7 {
+		t.Errorf("Expected %d remaining repeats stored got %d", 7, record.Remaining)
+	}
+	if !record.LastRun.Equal(now) {
+		t.Errorf("Expected last run %v got %v", now, record.LastRun)
+	}
+}
+
+func TestSchedulerRestoreSkipsMissedRuns(t *testing.T) {
+	now := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	fc := clock.NewMockClock(now)
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "jobs.json"))
+	store.Save(JobRecord{Name: "match", Interval: time.Minute, Remaining: -1, LastRun: now.Add(-3*time.Minute - 30*time.Second)})
+
+	s := New()
+	s.UseStore(store, CatchUpSkip)
+
+	runs := make(chan struct{})
+	job := Every(newManualTicker())
+	job.clock = fc
+	s.Add("match", job)
+	job.DoFunc(func() {
+		runs <- struct{}{}
+	})
+
+	// no catch up, first run keeps to the cadence of the last run
+	expectNoRun(t, runs)
+	fc.AddTime(30 * time.Second)
+	<-runs
+
+	job.Stop()
+	job.Wait()
+}
+
+func TestSchedulerForgetsFinishedJobs(t *testing.T) {
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "jobs.json"))
+
+	first := New()
+	first.UseStore(store, CatchUpSkip)
+	job := Every(newManualTicker()).Repeat(1)
+	first.Add("match", job)
+	job.DoFunc(func() {})
+	first.Wait()
+
+	if _, ok, _ := store.Load("match"); ok {
+		t.Errorf("Expected record of finished job to be deleted")
+	}
+
+	// a job added again under the name runs as configured
+	second := New()
+	second.UseStore(store, CatchUpSkip)
+	runs := make(chan struct{})
+	ticker := newManualTicker()
+	job = Every(ticker).Repeat(3)
+	second.Add("match", job)
+	job.DoFunc(func() {
+		runs <- struct{}{}
+	})
+	<-runs
+	for i := 0; i < 2; i++ {
+		ticker.tick()
+		<-runs
+	}
+	second.Wait()
+}
+
+func TestSchedulerStopDeletesRecord(t *testing.T) {
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "jobs.json"))
+	// left behind with nothing to run, it doesn't turn the new job off
+	store.Save(JobRecord{Name: "match", Interval: time.Minute, Remaining: 0})
+
+	s := New()
+	s.UseStore(store, CatchUpSkip)
+	ticker := newManualTicker()
+	runs := make(chan struct{})
+	job := Every(ticker).Repeat(2)
+	s.Add("match", job)
+	if job.repeats != 2 {
+		t.Errorf("Expected %d repeats got %d", 2, job.repeats)
+	}
+	job.DoFunc(func() {
+		runs <- struct{}{}
+	})
+	<-runs
+
+	s.Stop("match")
+	s.Wait()
+	if _, ok, _ := store.Load("match"); ok {
+		t.Errorf("Expected record of removed job to be deleted")
+	}
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"time"
+)
+
+var (
+	Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	Weekend  = []time.Weekday{time.Saturday, time.Sunday}
+)
+
+// Window is a daily period a job is allowed to run in, e.g. 12:00-23:30
+// Europe/London on weekdays is
+//
+//	Window{Start: 12 * time.Hour, End: 23*time.Hour + 30*time.Minute, Weekdays: Weekdays, Location: london}
+type Window struct {
+	// Start and End of the period as offsets from midnight. A period ending
+	// before it starts spans midnight, equal offsets cover the whole day.
+	Start, End time.Duration
+	// Days the period starts on, every day when empty
+	Weekdays []time.Weekday
+	// Dates the period is not active on, only the calendar date is compared
+	Exclude []time.Time
+	// Location the window is evaluated in, UTC when nil
+	Location *time.Location
+}
+
+// Contains reports if the time falls within the window
+func (w Window) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	// wall clock time rather than time elapsed since midnight, which differs on DST days
+	hour, min, sec := t.Clock()
+	offset := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+
+	switch {
+	case w.Start == w.End:
+	case w.Start < w.End:
+		if offset < w.Start || offset >= w.End {
+			return false
+		}
+	case offset >= w.End && offset < w.Start:
+		return false
+	case offset < w.End:
+		// early hours belong to the period that started the day before
+		midnight = midnight.AddDate(0, 0, -1)
+	}
+
+	return w.activeOn(midnight)
+}
+
+func (w Window) activeOn(day time.Time) bool {
+	for _, excluded := range w.Exclude {
+		y, m, d := excluded.Date()
+		if y == day.Year() && m == day.Month() && d == day.Day() {
+			return false
+		}
+	}
+
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range w.Weekdays {
+		if weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Within restricts the job to the given windows, ticks outside of all of
+// them are dropped while the job itself keeps running
+func (j *Job) Within(windows ...Window) *Job {
+	j.windows = append(j.windows, windows...)
+	return j
+}
+
+func (j *Job) inWindow(t time.Time) bool {
+	if len(j.windows) == 0 {
+		return true
+	}
+	for _, w := range j.windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mixer/clock"
+)
+
+func TestWindowContains(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	matchDays := Window{
+		Start:    12 * time.Hour,
+		End:      23*time.Hour + 30*time.Minute,
+		Weekdays: Weekdays,
+		Exclude:  []time.Time{time.Date(2024, time.December, 25, 0, 0, 0, 0, time.UTC)},
+		Location: london,
+	}
+	overnight := Window{Start: 22 * time.Hour, End: 2 * time.Hour, Weekdays: []time.Weekday{time.Friday}}
+	everyDay := Window{Start: 12 * time.Hour, End: 23*time.Hour + 30*time.Minute, Location: london}
+
+	testCases := []struct {
+		name   string
+		window Window
+		at     time.Time
+		want   bool
+	}{
+		{
+			name:   "weekday inside window",
+			window: matchDays,
+			at:     time.Date(2024, time.January, 10, 15, 0, 0, 0, london),
+			want:   true,
+		},
+		{
+			name:   "weekday before window opens",
+			window: matchDays,
+			at:     time.Date(2024, time.January, 10, 11, 59, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "window end is exclusive",
+			window: matchDays,
+			at:     time.Date(2024, time.January, 10, 23, 30, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "weekend outside window",
+			window: matchDays,
+			at:     time.Date(2024, time.January, 13, 15, 0, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "excluded date",
+			window: matchDays,
+			at:     time.Date(2024, time.December, 25, 15, 0, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "evaluated in window's timezone during summer time",
+			window: matchDays,
+			// 12:30 in London
+			at:   time.Date(2024, time.July, 10, 11, 30, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			name:   "wall clock time on the day clocks go forward",
+			window: everyDay,
+			at:     time.Date(2024, time.March, 31, 12, 15, 0, 0, london),
+			want:   true,
+		},
+		{
+			name:   "wall clock time after the window on the day clocks go forward",
+			window: everyDay,
+			at:     time.Date(2024, time.March, 31, 23, 45, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "wall clock time on the day clocks go back",
+			window: everyDay,
+			at:     time.Date(2024, time.October, 27, 11, 45, 0, 0, london),
+			want:   false,
+		},
+		{
+			name:   "overnight window after midnight belongs to previous day",
+			window: overnight,
+			at:     time.Date(2024, time.January, 13, 1, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "overnight window not started on a saturday",
+			window: overnight,
+			at:     time.Date(2024, time.January, 13, 23, 0, 0, 0, time.UTC),
+			want:   false,
+		},
+		{
+			name:   "equal start and end covers the whole day",
+			window: Window{Weekdays: Weekend},
+			at:     time.Date(2024, time.January, 13, 3, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.window.Contains(tc.at); got != tc.want {
+				t.Errorf("Expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJobSuppressesTicksOutsideWindow(t *testing.T) {
+	// Saturday morning
+	fc := clock.NewMockClock(time.Date(2024, time.January, 13, 9, 0, 0, 0, time.UTC))
+	ticker := newManualTicker()
+	runs := make(chan struct{})
+
+	job := Every(ticker).Within(Window{Start: 12 * time.Hour, End: 18 * time.Hour})
+	job.clock = fc
+	job.Repeat(1).DoFunc(func() {
+		runs <- struct{}{}
+	})
+
+	ticker.tick()
+	expectNoRun(t, runs)
+	if job.State() != Running {
+		t.Errorf("Expected job to keep %v got %v", Running, job.State())
+	}
+
+	fc.AddTime(3 * time.Hour)
+	ticker.tick()
+	<-runs
+	job.Wait()
+}